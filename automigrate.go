@@ -0,0 +1,257 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// DifferenceKind tells how a table differs from its schema
+type DifferenceKind string
+
+const (
+	// DifferenceExtraColumn is a column of the table that is not in the schema (e.g.: the field was removed)
+	DifferenceExtraColumn DifferenceKind = "extra column"
+	// DifferenceColumnType is a column whose type is not the type of the schema
+	DifferenceColumnType DifferenceKind = "column type"
+	// DifferenceMissingColumn is a column of the schema that cannot be added to the table (primary keys and autoincrement columns)
+	DifferenceMissingColumn DifferenceKind = "missing column"
+	// DifferenceMissingForeignKey is the FOREIGN KEY constraint of a column that was added to the table
+	DifferenceMissingForeignKey DifferenceKind = "missing foreign key"
+	// DifferenceMissingNotNull is a NOT NULL column without default that was added to the table as nullable (its existing rows have no value)
+	DifferenceMissingNotNull DifferenceKind = "missing not null"
+)
+
+// SchemaDifference describes a difference between a schema and its table that AutoMigrate does not apply
+type SchemaDifference struct {
+	Table    string
+	Column   string
+	Kind     DifferenceKind
+	Expected string
+	Actual   string
+}
+
+// String gets a string representation of this SchemaDifference
+//
+// implements the fmt.Stringer interface
+func (difference SchemaDifference) String() string {
+	if len(difference.Expected) > 0 || len(difference.Actual) > 0 {
+		return fmt.Sprintf("%s.%s: %s (expected: %s, actual: %s)", difference.Table, difference.Column, difference.Kind, difference.Expected, difference.Actual)
+	}
+	return fmt.Sprintf("%s.%s: %s", difference.Table, difference.Column, difference.Kind)
+}
+
+// AutoMigrate updates the SQL tables of the given schemas so they match the schemas
+//
+// Missing tables are created (see CreateTable), missing columns and indexes are added to the existing tables.
+// The differences that would lose data or that cannot be applied safely (extra columns, changed types, etc)
+// are not applied, they are returned so they can be dealt with in a Migration.
+//
+// Fields that are NOT NULL without a default (see the default tag option) are added as nullable columns,
+// as the rows of the table have no value for them, the missing NOT NULL is returned as a difference.
+//
+// The Dialect must be an Introspector, its catalog tells which tables exist
+func (db *DB) AutoMigrate(schemas ...interface{}) ([]SchemaDifference, error) {
+	return db.AutoMigrateContext(context.Background(), schemas...)
+}
+
+// AutoMigrateContext updates the SQL tables of the given schemas so they match the schemas
+//
+// If the context contains a transaction (see Tx.ToContext), the existing tables are inspected and changed within it
+func (db *DB) AutoMigrateContext(ctx context.Context, schemas ...interface{}) ([]SchemaDifference, error) {
+	return db.session(ctx).autoMigrate(ctx, schemas)
+}
+
+// autoMigrate updates the SQL tables of the given schemas so they match the schemas
+func (session *session) autoMigrate(ctx context.Context, schemas []interface{}) ([]SchemaDifference, error) {
+	sorted, err := sortSchemas(schemas, session.Naming)
+	if err != nil {
+		return []SchemaDifference{}, err
+	}
+	differences := []SchemaDifference{}
+	for _, schema := range sorted {
+		schemaType, _ := getTypeAndValue(schema)
+		info := getSchemaInfo(schemaType, session.Naming)
+		log := session.Logger.Child(nil, "automigrate").Record("table", info.Table)
+
+		columns, exists, err := session.existingColumns(ctx, info.Table)
+		if err != nil {
+			return differences, err
+		}
+		if !exists {
+			log.Infof("Table %s does not exist, creating it", info.Table)
+			if err := session.createTable(ctx, schema); err != nil {
+				return differences, err
+			}
+			continue
+		}
+		compositeKey := len(info.Keys()) > 1
+		for _, field := range info.Fields {
+			definition, sqltype, constraint, err := session.columnDefinition(log, field, compositeKey)
+			if err != nil {
+				return differences, err
+			}
+			actual, found := columns[strings.ToLower(field.Column)]
+			if found {
+				delete(columns, strings.ToLower(field.Column))
+				if len(actual) > 0 && normalizeSQLType(actual) != normalizeSQLType(sqltype) {
+					differences = append(differences, SchemaDifference{Table: info.Table, Column: field.Column, Kind: DifferenceColumnType, Expected: sqltype, Actual: actual})
+				}
+				continue
+			}
+			if field.Options.PrimaryKey || field.Options.AutoIncrement {
+				differences = append(differences, SchemaDifference{Table: info.Table, Column: field.Column, Kind: DifferenceMissingColumn, Expected: sqltype})
+				continue
+			}
+			notNull := !field.Options.Nullable && len(field.Options.Default) == 0
+			if notNull {
+				nullable := field
+				nullable.Options.Nullable = true
+				if definition, _, _, err = session.columnDefinition(log, nullable, compositeKey); err != nil {
+					return differences, err
+				}
+			}
+			statement := session.Dialect.AddColumn(info.Table, definition)
+			log.Infof("Adding column %s", field.Column)
+			log.Tracef("Statement: %s", statement)
+			if _, err := session.exec.ExecContext(ctx, statement); err != nil {
+				return differences, errors.CreationFailed.With("column", field.Column).Wrap(err)
+			}
+			if notNull {
+				differences = append(differences, SchemaDifference{Table: info.Table, Column: field.Column, Kind: DifferenceMissingNotNull, Expected: "NOT NULL"})
+			}
+			if len(constraint) > 0 {
+				differences = append(differences, SchemaDifference{Table: info.Table, Column: field.Column, Kind: DifferenceMissingForeignKey, Expected: constraint})
+			}
+		}
+		extras := make([]string, 0, len(columns))
+		for column := range columns {
+			extras = append(extras, column)
+		}
+		sort.Strings(extras)
+		for _, column := range extras {
+			differences = append(differences, SchemaDifference{Table: info.Table, Column: column, Kind: DifferenceExtraColumn, Actual: columns[column]})
+		}
+		if len(info.Indexes) > 0 {
+			indexes, err := session.missingIndexes(ctx, info.Table, info.Indexes)
+			if err != nil {
+				return differences, err
+			}
+			if err := session.createIndexes(ctx, info.Table, indexes); err != nil {
+				return differences, err
+			}
+		}
+	}
+	for _, difference := range differences {
+		session.Logger.Child(nil, "automigrate").Warnf("Not applied: %s", difference)
+	}
+	return differences, nil
+}
+
+// existingColumns returns the columns of a table and their database type (empty if the driver does not tell)
+//
+// The catalog of the database tells first if the table exists, as querying a missing table would abort a PostgreSQL transaction
+func (session *session) existingColumns(ctx context.Context, table string) (columns map[string]string, exists bool, err error) {
+	missing, err := session.missingTable(ctx, table)
+	if err != nil {
+		return nil, false, err
+	}
+	if missing {
+		return nil, false, nil
+	}
+	rows, err := session.exec.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", session.Dialect.Quote(table)))
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, false, err
+	}
+	columns = map[string]string{}
+	for _, columnType := range types {
+		columns[strings.ToLower(columnType.Name())] = columnType.DatabaseTypeName()
+	}
+	return columns, true, nil
+}
+
+// missingTable tells if the catalog of the database describes no column for a table
+func (session *session) missingTable(ctx context.Context, table string) (bool, error) {
+	introspector, err := session.DB.introspector()
+	if err != nil {
+		return false, err
+	}
+	queries, parms := introspector.DescribeQueries(table)
+	rows, err := session.exec.QueryContext(ctx, queries.Columns, parms...)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		return false, nil
+	}
+	if err := rows.Err(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// missingIndexes returns the indexes that do not exist in a table
+func (session *session) missingIndexes(ctx context.Context, table string, indexes []Index) ([]Index, error) {
+	introspector, err := session.DB.introspector()
+	if err != nil {
+		return []Index{}, err
+	}
+	queries, parms := introspector.DescribeQueries(table)
+	existingIndexes, err := session.DB.describeIndexes(ctx, queries.Indexes, parms)
+	if err != nil {
+		return []Index{}, err
+	}
+	existing := map[string]bool{}
+	for _, index := range existingIndexes {
+		existing[strings.ToLower(index.Name)] = true
+	}
+	missing := []Index{}
+	for _, index := range indexes {
+		if !existing[strings.ToLower(index.Name)] {
+			missing = append(missing, index)
+		}
+	}
+	return missing, nil
+}
+
+// sqlTypeAliases maps the names databases give to the SQL types to the names used by the Dialects
+var sqlTypeAliases = map[string]string{
+	"INTEGER":                     "INT",
+	"INT4":                        "INT",
+	"SERIAL":                      "INT",
+	"INT8":                        "BIGINT",
+	"BIGSERIAL":                   "BIGINT",
+	"BOOLEAN":                     "BOOL",
+	"BIT":                         "BOOL",
+	"TINYINT":                     "BOOL",
+	"CHARACTER VARYING":           "VARCHAR",
+	"NVARCHAR":                    "VARCHAR",
+	"DOUBLE PRECISION":            "FLOAT8",
+	"DOUBLE":                      "FLOAT8",
+	"FLOAT":                       "FLOAT8",
+	"TIMESTAMP WITHOUT TIME ZONE": "TIMESTAMP",
+	"DATETIME":                    "TIMESTAMP",
+	"DATETIME2":                   "TIMESTAMP",
+	"UNIQUEIDENTIFIER":            "UUID",
+}
+
+// normalizeSQLType normalizes an SQL type so types can be compared: the size is removed and aliases are resolved
+func normalizeSQLType(sqltype string) string {
+	sqltype = strings.ToUpper(strings.TrimSpace(sqltype))
+	if i := strings.Index(sqltype, "("); i >= 0 {
+		sqltype = strings.TrimSpace(sqltype[:i])
+	}
+	if alias, found := sqlTypeAliases[sqltype]; found {
+		return alias
+	}
+	return sqltype
+}
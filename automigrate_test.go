@@ -0,0 +1,223 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	"github.com/stretchr/testify/suite"
+)
+
+type AutoMigrateSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+type GardenV1 struct {
+	ID   string `sql:"key"`
+	Name string
+}
+
+func (garden GardenV1) TableName() string { return "garden" }
+
+type GardenV2 struct {
+	ID   string `sql:"key"`
+	Name string
+	Size *int
+	Open bool `sql:"default=TRUE"`
+}
+
+func (garden GardenV2) TableName() string { return "garden" }
+
+type GardenV3 struct {
+	ID   string `sql:"key"`
+	Size *int
+	Open bool `sql:"default=TRUE"`
+}
+
+func (garden GardenV3) TableName() string { return "garden" }
+
+func TestAutoMigrateSuite(t *testing.T) {
+	suite.Run(t, new(AutoMigrateSuite))
+}
+
+// describeTable makes the catalog of the given Introspector describe a table, and the table return the given columns ("name TYPE")
+func describeTable(name string, introspector sql.Introspector, table string, columns ...string) {
+	catalog, _ := introspector.DescribeQueries(table)
+	rows := make([][]driver.Value, 0, len(columns))
+	for _, column := range columns {
+		parts := strings.SplitN(column, " ", 2)
+		rows = append(rows, []driver.Value{parts[0], parts[1], "YES", nil})
+	}
+	Respond(name, catalog.Columns, []string{"column_name VARCHAR", "data_type VARCHAR", "is_nullable VARCHAR", "column_default VARCHAR"}, rows...)
+	Respond(name, fmt.Sprintf("SELECT * FROM %s WHERE 1 = 0", table), columns)
+}
+
+func (suite *AutoMigrateSuite) TestCanAutoMigrate() {
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries("garden")
+
+	differences, err := db.AutoMigrate(GardenV1{})
+	suite.Require().Nil(err, "Failed to create the table")
+	suite.Assert().Empty(differences)
+
+	describeTable(name, sql.PostgresDialect{}, "garden", "id VARCHAR", "name VARCHAR")
+	differences, err = db.AutoMigrate(GardenV2{})
+	suite.Require().Nil(err, "Failed to add the columns")
+	suite.Assert().Empty(differences)
+
+	describeTable(name, sql.PostgresDialect{}, "garden", "id VARCHAR", "name VARCHAR", "size INT4", "open BOOL")
+	differences, err = db.AutoMigrate(GardenV3{})
+	suite.Require().Nil(err, "Failed to migrate")
+	suite.Require().Len(differences, 1, "The removed field should be reported")
+	suite.Assert().Equal(sql.DifferenceExtraColumn, differences[0].Kind)
+	suite.Assert().Equal("name", differences[0].Column)
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"CREATE TABLE garden (id VARCHAR(80) PRIMARY KEY, name VARCHAR(80) NOT NULL)",
+		catalog.Columns,
+		"SELECT * FROM garden WHERE 1 = 0",
+		"ALTER TABLE garden ADD COLUMN size INT",
+		"ALTER TABLE garden ADD COLUMN open BOOL NOT NULL DEFAULT TRUE",
+		catalog.Columns,
+		"SELECT * FROM garden WHERE 1 = 0",
+	}, Recorded(name), "The extra column should not be dropped")
+}
+
+func (suite *AutoMigrateSuite) TestCanAutoMigrateInTransaction() {
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries("garden")
+
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		_, err := db.AutoMigrateContext(tx.Context(), GardenV1{})
+		return err
+	})
+	suite.Require().Nil(err, "Failed to create the table")
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		catalog.Columns,
+		"CREATE TABLE garden (id VARCHAR(80) PRIMARY KEY, name VARCHAR(80) NOT NULL)",
+		"COMMIT",
+	}, Recorded(name), "The missing table should not be queried, as it would abort the transaction")
+}
+
+func (suite *AutoMigrateSuite) TestShouldNotCreateTableThatCannotBeRead() {
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries("garden")
+	Fail(name, catalog.Columns, fmt.Errorf(`permission denied for schema public`))
+
+	_, err = db.AutoMigrate(GardenV1{})
+	suite.Require().NotNil(err, "Should not migrate a table that cannot be read")
+	suite.Assert().Contains(err.Error(), "permission denied")
+
+	Fail(name, catalog.Columns, nil)
+	describeTable(name, sql.PostgresDialect{}, "garden", "id VARCHAR", "name VARCHAR")
+	Fail(name, "SELECT * FROM garden WHERE 1 = 0", fmt.Errorf(`permission denied for table garden`))
+	_, err = db.AutoMigrate(GardenV1{})
+	suite.Require().NotNil(err, "Should not migrate a table that cannot be read")
+	suite.Assert().Contains(err.Error(), "permission denied")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		catalog.Columns,
+		"SELECT * FROM garden WHERE 1 = 0",
+	}, Recorded(name), "The table should not be created")
+}
+
+func (suite *AutoMigrateSuite) TestCanAddColumnsAndIndexes() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	describeTable(suite.T().Name(), sql.PostgresDialect{}, "account", "id VARCHAR", "email VARCHAR", "tenant INT4", "legacy TEXT")
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries("account")
+	Respond(suite.T().Name(), catalog.Indexes, []string{"relname NAME", "attname NAME", "indisunique BOOL", "indpred TEXT"}, []driver.Value{"account_email_idx", "email", true, ""})
+
+	differences, err := db.AutoMigrate(Account{})
+	suite.Require().Nil(err, "Failed to migrate")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"SELECT * FROM account WHERE 1 = 0",
+		"ALTER TABLE account ADD COLUMN name VARCHAR(80)",
+		"ALTER TABLE account ADD COLUMN active BOOL",
+		catalog.Indexes,
+		"CREATE INDEX account_tenant_name ON account (tenant, name)",
+		"CREATE INDEX account_active_idx ON account (active) WHERE active",
+	}, Recorded(suite.T().Name()), "The NOT NULL columns without default should be added as nullable")
+	suite.Assert().Equal([]sql.SchemaDifference{
+		{Table: "account", Column: "tenant", Kind: sql.DifferenceColumnType, Expected: "VARCHAR(40)", Actual: "INT4"},
+		{Table: "account", Column: "name", Kind: sql.DifferenceMissingNotNull, Expected: "NOT NULL"},
+		{Table: "account", Column: "active", Kind: sql.DifferenceMissingNotNull, Expected: "NOT NULL"},
+		{Table: "account", Column: "legacy", Kind: sql.DifferenceExtraColumn, Actual: "TEXT"},
+	}, differences)
+	suite.Assert().Equal("account.tenant: column type (expected: VARCHAR(40), actual: INT4)", differences[0].String())
+}
+
+func (suite *AutoMigrateSuite) TestShouldNotAddKeyColumns() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.SQLServerDialect{}
+	describeTable(suite.T().Name(), sql.SQLServerDialect{}, "ticket", "title NVARCHAR", "status NVARCHAR", "priority INT", "assignee NVARCHAR", "opened DATETIME2")
+	catalog, _ := sql.SQLServerDialect{}.DescribeQueries("ticket")
+
+	differences, err := db.AutoMigrate(Ticket{})
+	suite.Require().Nil(err, "Failed to migrate")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"SELECT * FROM ticket WHERE 1 = 0",
+		"ALTER TABLE ticket ADD notes NVARCHAR(80)",
+	}, Recorded(suite.T().Name()))
+	suite.Require().Len(differences, 1)
+	suite.Assert().Equal(sql.DifferenceMissingColumn, differences[0].Kind)
+	suite.Assert().Equal("id", differences[0].Column)
+}
+
+// Suite Tools
+
+func (suite *AutoMigrateSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *AutoMigrateSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *AutoMigrateSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *AutoMigrateSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
@@ -10,12 +10,18 @@ import (
 )
 
 type DB struct {
-	db     *gosql.DB
-	Logger *logger.Logger
+	db         *gosql.DB
+	statements *statementCache
+	Dialect    Dialect
+	Naming     NamingStrategy
+	Logger     *logger.Logger
 }
 
 type key int
-const dbContextKey key = iota * 31415
+const (
+	dbContextKey key = iota * 31415
+	txContextKey
+)
 
 // Open opens a database specified by its database driver name and a driver-specific data source name,
 // usually consisting of at least a database name and connection information.
@@ -29,12 +35,18 @@ const dbContextKey key = iota * 31415
 //
 // The returned DB is safe for concurrent use by multiple goroutines and maintains its own pool of idle connections.
 // Thus, the Open function should be called just once. It is rarely necessary to close a DB.
+//
+// The Dialect of the DB is picked from the driver name (see RegisterDialect)
+//
+// The structured operations (Insert, FindAll, etc) prepare their statements once and keep the most recently used ones for the life of the DB
 func Open(drivername string, datasourceName string, l *logger.Logger) (db *DB, err error) {
 	db = &DB{
-		Logger: logger.CreateIfNil(l, "sql").Child("db", "db"),
+		Dialect: GetDialect(drivername),
+		Logger:  logger.CreateIfNil(l, "sql").Child("db", "db"),
 	}
 
 	db.db, err = gosql.Open(drivername, datasourceName)
+	db.statements = newStatementCache(db.db)
 	return db, errors.RuntimeError.Wrap(err)
 }
 
@@ -85,6 +97,11 @@ func (db *DB) QueryRowContext(ctx context.Context, query string, args ...interfa
 // It is rare to Close a DB, as the DB handle is meant to be long-lived and shared between many goroutines.
 func (db *DB) Close() error {
 	db.Logger.Infof("Closing Database Connection")
+	if db.statements != nil {
+		if err := db.statements.Close(); err != nil {
+			db.Logger.Errorf("Failed to close the prepared statements", err)
+		}
+	}
 	return db.db.Close()
 }
 
@@ -0,0 +1,184 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// MySQLDialect is the Dialect for MySQL and MariaDB databases
+type MySQLDialect struct{}
+
+var mysqlTypes = sqlTypes{
+	UUID:   "CHAR(36)",
+	Time:   "DATETIME(6)",
+	Bool:   "BOOLEAN",
+	Float:  "DOUBLE",
+	String: "VARCHAR(80)",
+	Int:    "INT",
+	BigInt: "BIGINT",
+}
+
+// Name returns the name of this Dialect
+func (dialect MySQLDialect) Name() string {
+	return "mysql"
+}
+
+// Placeholder returns the placeholder for the parameter at the given position (starting at 1)
+//
+// MySQL uses ?
+func (dialect MySQLDialect) Placeholder(position int) string {
+	return "?"
+}
+
+// Quote quotes the given identifier if it needs to be (reserved word, special characters)
+//
+// MySQL quotes identifiers with backticks
+func (dialect MySQLDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, "`", "`")
+}
+
+// SQLType returns the SQL type that matches the given GO type
+func (dialect MySQLDialect) SQLType(name string, t reflect.Type) (string, error) {
+	return mysqlTypes.get(name, t)
+}
+
+// Savepoint returns the statement that creates a savepoint in the current transaction
+func (dialect MySQLDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + dialect.Quote(name)
+}
+
+// ReleaseSavepoint returns the statement that releases a savepoint
+func (dialect MySQLDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + dialect.Quote(name)
+}
+
+// RollbackToSavepoint returns the statement that rolls back the current transaction to a savepoint
+func (dialect MySQLDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + dialect.Quote(name)
+}
+
+// AutoIncrement returns the clause that makes a column generate its values automatically
+//
+// MySQL only supports AUTO_INCREMENT on a column that is a key, it is supported on the primary key
+func (dialect MySQLDialect) AutoIncrement(primaryKey bool) (string, error) {
+	if !primaryKey {
+		return "", errors.Unsupported.With("autoincrement", "column is not a primary key").WithStack()
+	}
+	return "AUTO_INCREMENT", nil
+}
+
+// CreateIndex returns the statement that creates an index on a table
+//
+// MySQL does not support partial indexes
+func (dialect MySQLDialect) CreateIndex(table string, index Index) (string, error) {
+	return createIndex(dialect, table, index, false)
+}
+
+// Paginate returns the ORDER BY, LIMIT, and OFFSET clauses of a SELECT statement, or an empty string
+//
+// MySQL does not support NULLS FIRST/LAST, which are emulated, and needs a LIMIT when there is an OFFSET
+func (dialect MySQLDialect) Paginate(sorts []Sort, limit, offset int, parms []interface{}) (string, []interface{}) {
+	return paginate(dialect, sorts, false, limit, offset, "18446744073709551615", parms)
+}
+
+// TransactionalDDL tells if the statements that change the schema (CREATE TABLE, ALTER TABLE, etc) can run in a transaction
+//
+// MySQL commits the current transaction before running these statements
+func (dialect MySQLDialect) TransactionalDDL() bool {
+	return false
+}
+
+// CountsMatchedRows tells if the rows affected by an UPDATE are the rows it matched, and not only the rows it changed
+//
+// MySQL counts only the rows that changed, unless the connection sets CLIENT_FOUND_ROWS (clientFoundRows=true with go-sql-driver/mysql).
+// As the connection cannot be checked, an UPDATE that affected no row is checked with a SELECT COUNT(*)
+func (dialect MySQLDialect) CountsMatchedRows() bool {
+	return false
+}
+
+// AddColumn returns the statement that adds a column to a table
+func (dialect MySQLDialect) AddColumn(table, definition string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " ADD COLUMN " + definition
+}
+
+// RenameColumn returns the statement that renames a column of a table
+//
+// MySQL supports it since version 8.0
+func (dialect MySQLDialect) RenameColumn(table, column, newName string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " RENAME COLUMN " + dialect.Quote(column) + " TO " + dialect.Quote(newName)
+}
+
+// DropColumn returns the statement that removes a column from a table
+func (dialect MySQLDialect) DropColumn(table, column string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " DROP COLUMN " + dialect.Quote(column)
+}
+
+// Upsert returns the statement that inserts a row in a table, or updates the existing row that conflicts with it
+//
+// MySQL uses INSERT ... ON DUPLICATE KEY UPDATE, which detects the conflicts on all the unique indexes, the conflict columns are ignored.
+// The inserted values are read with VALUES(column), which MariaDB and all the versions of MySQL understand
+// (MySQL 8.0.20 deprecates it in favor of a row alias that MariaDB does not support).
+// To keep the existing row, its first column is set to itself
+func (dialect MySQLDialect) Upsert(table string, columns, conflict, update []string) (string, error) {
+	if len(columns) == 0 {
+		return "", errors.ArgumentMissing.With("columns").WithStack()
+	}
+	sets := make([]string, 0, len(update))
+	for _, column := range update {
+		sets = append(sets, fmt.Sprintf("%s = VALUES(%s)", dialect.Quote(column), dialect.Quote(column)))
+	}
+	if len(sets) == 0 {
+		sets = append(sets, fmt.Sprintf("%s = %s", dialect.Quote(columns[0]), dialect.Quote(columns[0])))
+	}
+	return insertClause(dialect, table, columns) + " ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", "), nil
+}
+
+// InsertReturning returns an empty string, MySQL cannot return the inserted row
+func (dialect MySQLDialect) InsertReturning(table string, columns, returning []string) string {
+	return ""
+}
+
+// MaxInsertRows returns the maximum number of rows an INSERT statement can insert at once
+//
+// MySQL accepts up to 65535 parameters per statement
+func (dialect MySQLDialect) MaxInsertRows(columns int) int {
+	return maxInsertRows(65535, columns)
+}
+
+// CopyIn returns an empty string, MySQL cannot copy rows in bulk through database/sql
+func (dialect MySQLDialect) CopyIn(table string, columns []string) string {
+	return ""
+}
+
+// TablesQuery returns the query that lists the tables of the current database
+//
+// implements the Introspector interface
+func (dialect MySQLDialect) TablesQuery() string {
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name"
+}
+
+// DescribeQueries returns the queries that describe a table
+//
+// Tables without a namespace are looked up in the current database.
+//
+// implements the Introspector interface
+func (dialect MySQLDialect) DescribeQueries(table string) (CatalogQueries, []interface{}) {
+	schema, parms := "DATABASE()", []interface{}{table}
+	if namespace, name := splitTable(table); len(namespace) > 0 {
+		schema, parms = "?", []interface{}{namespace, name}
+	}
+	return CatalogQueries{
+		Columns: fmt.Sprintf("SELECT column_name, upper(column_type), is_nullable = 'YES', column_default "+
+			"FROM information_schema.columns WHERE table_schema = %s AND table_name = ? ORDER BY ordinal_position", schema),
+		PrimaryKey: fmt.Sprintf("SELECT column_name FROM information_schema.key_column_usage "+
+			"WHERE constraint_name = 'PRIMARY' AND table_schema = %s AND table_name = ? ORDER BY ordinal_position", schema),
+		Indexes: fmt.Sprintf("SELECT index_name, column_name, non_unique = 0, '' FROM information_schema.statistics "+
+			"WHERE table_schema = %s AND table_name = ? AND index_name <> 'PRIMARY' ORDER BY index_name, seq_in_index", schema),
+		ForeignKeys: fmt.Sprintf("SELECT kcu.constraint_name, kcu.column_name, kcu.referenced_table_name, kcu.referenced_column_name, rc.delete_rule, rc.update_rule FROM information_schema.key_column_usage kcu "+
+			"JOIN information_schema.referential_constraints rc ON rc.constraint_schema = kcu.constraint_schema AND rc.constraint_name = kcu.constraint_name "+
+			"WHERE kcu.table_schema = %s AND kcu.table_name = ? ORDER BY kcu.constraint_name, kcu.ordinal_position", schema),
+	}, parms
+}
@@ -0,0 +1,164 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// PostgresDialect is the Dialect for PostgreSQL databases
+//
+// It is also the default Dialect when the driver is unknown
+type PostgresDialect struct{}
+
+var postgresTypes = sqlTypes{
+	UUID:   "UUID",
+	Time:   "TIMESTAMP",
+	Bool:   "BOOL",
+	Float:  "FLOAT8",
+	String: "VARCHAR(80)",
+	Int:    "INT",
+	BigInt: "BIGINT",
+}
+
+// Name returns the name of this Dialect
+func (dialect PostgresDialect) Name() string {
+	return "postgres"
+}
+
+// Placeholder returns the placeholder for the parameter at the given position (starting at 1)
+//
+// PostgreSQL uses $1, $2, ...
+func (dialect PostgresDialect) Placeholder(position int) string {
+	return fmt.Sprintf("$%d", position)
+}
+
+// Quote quotes the given identifier if it needs to be (reserved word, special characters)
+//
+// PostgreSQL quotes identifiers with double quotes
+func (dialect PostgresDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, `"`, `"`)
+}
+
+// SQLType returns the SQL type that matches the given GO type
+func (dialect PostgresDialect) SQLType(name string, t reflect.Type) (string, error) {
+	return postgresTypes.get(name, t)
+}
+
+// Savepoint returns the statement that creates a savepoint in the current transaction
+func (dialect PostgresDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + dialect.Quote(name)
+}
+
+// ReleaseSavepoint returns the statement that releases a savepoint
+func (dialect PostgresDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + dialect.Quote(name)
+}
+
+// RollbackToSavepoint returns the statement that rolls back the current transaction to a savepoint
+func (dialect PostgresDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + dialect.Quote(name)
+}
+
+// AutoIncrement returns the clause that makes a column generate its values automatically
+//
+// PostgreSQL uses identity columns
+func (dialect PostgresDialect) AutoIncrement(primaryKey bool) (string, error) {
+	return "GENERATED BY DEFAULT AS IDENTITY", nil
+}
+
+// CreateIndex returns the statement that creates an index on a table
+func (dialect PostgresDialect) CreateIndex(table string, index Index) (string, error) {
+	return createIndex(dialect, table, index, true)
+}
+
+// Paginate returns the ORDER BY, LIMIT, and OFFSET clauses of a SELECT statement, or an empty string
+func (dialect PostgresDialect) Paginate(sorts []Sort, limit, offset int, parms []interface{}) (string, []interface{}) {
+	return paginate(dialect, sorts, true, limit, offset, "", parms)
+}
+
+// TransactionalDDL tells if the statements that change the schema (CREATE TABLE, ALTER TABLE, etc) can run in a transaction
+func (dialect PostgresDialect) TransactionalDDL() bool {
+	return true
+}
+
+// CountsMatchedRows tells if the rows affected by an UPDATE are the rows it matched, and not only the rows it changed
+func (dialect PostgresDialect) CountsMatchedRows() bool {
+	return true
+}
+
+// AddColumn returns the statement that adds a column to a table
+func (dialect PostgresDialect) AddColumn(table, definition string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " ADD COLUMN " + definition
+}
+
+// RenameColumn returns the statement that renames a column of a table
+func (dialect PostgresDialect) RenameColumn(table, column, newName string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " RENAME COLUMN " + dialect.Quote(column) + " TO " + dialect.Quote(newName)
+}
+
+// DropColumn returns the statement that removes a column from a table
+func (dialect PostgresDialect) DropColumn(table, column string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " DROP COLUMN " + dialect.Quote(column)
+}
+
+// Upsert returns the statement that inserts a row in a table, or updates the existing row that conflicts with it
+//
+// PostgreSQL uses INSERT ... ON CONFLICT
+func (dialect PostgresDialect) Upsert(table string, columns, conflict, update []string) (string, error) {
+	return onConflict(dialect, table, columns, conflict, update)
+}
+
+// InsertReturning returns the statement that inserts a row in a table and returns the given columns of the inserted row
+//
+// PostgreSQL uses INSERT ... RETURNING
+func (dialect PostgresDialect) InsertReturning(table string, columns, returning []string) string {
+	return insertClause(dialect, table, columns) + " RETURNING " + quoteColumns(dialect, returning, "")
+}
+
+// MaxInsertRows returns the maximum number of rows an INSERT statement can insert at once
+//
+// PostgreSQL accepts up to 65535 parameters per statement
+func (dialect PostgresDialect) MaxInsertRows(columns int) int {
+	return maxInsertRows(65535, columns)
+}
+
+// CopyIn returns the statement that copies rows into a table in bulk
+//
+// PostgreSQL uses COPY ... FROM STDIN, the driver must support it (e.g.: github.com/lib/pq)
+func (dialect PostgresDialect) CopyIn(table string, columns []string) string {
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN", dialect.Quote(table), quoteColumns(dialect, columns, ""))
+}
+
+// TablesQuery returns the query that lists the tables of the current schema
+//
+// implements the Introspector interface
+func (dialect PostgresDialect) TablesQuery() string {
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name"
+}
+
+// DescribeQueries returns the queries that describe a table
+//
+// Tables without a namespace are looked up in the current schema.
+//
+// implements the Introspector interface
+func (dialect PostgresDialect) DescribeQueries(table string) (CatalogQueries, []interface{}) {
+	schema, name, parms := "current_schema()", "$1", []interface{}{table}
+	if namespace, tablename := splitTable(table); len(namespace) > 0 {
+		schema, name, parms = "$1", "$2", []interface{}{namespace, tablename}
+	}
+	return CatalogQueries{
+		Columns: fmt.Sprintf("SELECT column_name, CASE WHEN character_maximum_length IS NULL THEN upper(data_type) ELSE upper(data_type) || '(' || character_maximum_length || ')' END, is_nullable = 'YES', column_default "+
+			"FROM information_schema.columns WHERE table_schema = %s AND table_name = %s ORDER BY ordinal_position", schema, name),
+		PrimaryKey: fmt.Sprintf("SELECT kcu.column_name FROM information_schema.table_constraints tc "+
+			"JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name "+
+			"WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = %s AND tc.table_name = %s ORDER BY kcu.ordinal_position", schema, name),
+		Indexes: fmt.Sprintf("SELECT i.relname, a.attname, ix.indisunique, COALESCE(pg_get_expr(ix.indpred, ix.indrelid), '') FROM pg_index ix "+
+			"JOIN pg_class t ON t.oid = ix.indrelid JOIN pg_namespace n ON n.oid = t.relnamespace JOIN pg_class i ON i.oid = ix.indexrelid "+
+			"JOIN LATERAL unnest(ix.indkey) WITH ORDINALITY AS k(attnum, position) ON true JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = k.attnum "+
+			"WHERE n.nspname = %s AND t.relname = %s AND NOT ix.indisprimary ORDER BY i.relname, k.position", schema, name),
+		ForeignKeys: fmt.Sprintf("SELECT rc.constraint_name, kcu.column_name, ccu.table_name, ccu.column_name, rc.delete_rule, rc.update_rule FROM information_schema.referential_constraints rc "+
+			"JOIN information_schema.key_column_usage kcu ON kcu.constraint_schema = rc.constraint_schema AND kcu.constraint_name = rc.constraint_name "+
+			"JOIN information_schema.key_column_usage ccu ON ccu.constraint_schema = rc.unique_constraint_schema AND ccu.constraint_name = rc.unique_constraint_name AND ccu.ordinal_position = kcu.position_in_unique_constraint "+
+			"WHERE kcu.table_schema = %s AND kcu.table_name = %s ORDER BY rc.constraint_name, kcu.ordinal_position", schema, name),
+	}, parms
+}
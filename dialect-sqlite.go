@@ -0,0 +1,164 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/gildas/go-errors"
+)
+
+// SQLiteDialect is the Dialect for SQLite databases
+type SQLiteDialect struct{}
+
+var sqliteTypes = sqlTypes{
+	UUID:   "TEXT",
+	Time:   "TIMESTAMP",
+	Bool:   "BOOLEAN",
+	Float:  "REAL",
+	String: "VARCHAR(80)",
+	Int:    "INTEGER",
+	BigInt: "INTEGER",
+}
+
+// Name returns the name of this Dialect
+func (dialect SQLiteDialect) Name() string {
+	return "sqlite"
+}
+
+// Placeholder returns the placeholder for the parameter at the given position (starting at 1)
+//
+// SQLite uses ?
+func (dialect SQLiteDialect) Placeholder(position int) string {
+	return "?"
+}
+
+// Quote quotes the given identifier if it needs to be (reserved word, special characters)
+//
+// SQLite quotes identifiers with double quotes
+func (dialect SQLiteDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, `"`, `"`)
+}
+
+// SQLType returns the SQL type that matches the given GO type
+func (dialect SQLiteDialect) SQLType(name string, t reflect.Type) (string, error) {
+	return sqliteTypes.get(name, t)
+}
+
+// Savepoint returns the statement that creates a savepoint in the current transaction
+func (dialect SQLiteDialect) Savepoint(name string) string {
+	return "SAVEPOINT " + dialect.Quote(name)
+}
+
+// ReleaseSavepoint returns the statement that releases a savepoint
+func (dialect SQLiteDialect) ReleaseSavepoint(name string) string {
+	return "RELEASE SAVEPOINT " + dialect.Quote(name)
+}
+
+// RollbackToSavepoint returns the statement that rolls back the current transaction to a savepoint
+func (dialect SQLiteDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TO SAVEPOINT " + dialect.Quote(name)
+}
+
+// AutoIncrement returns the clause that makes a column generate its values automatically
+//
+// SQLite only supports AUTOINCREMENT on INTEGER PRIMARY KEY columns
+func (dialect SQLiteDialect) AutoIncrement(primaryKey bool) (string, error) {
+	if !primaryKey {
+		return "", errors.Unsupported.With("autoincrement", "column is not a primary key").WithStack()
+	}
+	return "AUTOINCREMENT", nil
+}
+
+// CreateIndex returns the statement that creates an index on a table
+func (dialect SQLiteDialect) CreateIndex(table string, index Index) (string, error) {
+	return createIndex(dialect, table, index, true)
+}
+
+// Paginate returns the ORDER BY, LIMIT, and OFFSET clauses of a SELECT statement, or an empty string
+//
+// SQLite needs a LIMIT (-1 means no limit) when there is an OFFSET
+func (dialect SQLiteDialect) Paginate(sorts []Sort, limit, offset int, parms []interface{}) (string, []interface{}) {
+	return paginate(dialect, sorts, true, limit, offset, "-1", parms)
+}
+
+// TransactionalDDL tells if the statements that change the schema (CREATE TABLE, ALTER TABLE, etc) can run in a transaction
+func (dialect SQLiteDialect) TransactionalDDL() bool {
+	return true
+}
+
+// CountsMatchedRows tells if the rows affected by an UPDATE are the rows it matched, and not only the rows it changed
+func (dialect SQLiteDialect) CountsMatchedRows() bool {
+	return true
+}
+
+// AddColumn returns the statement that adds a column to a table
+func (dialect SQLiteDialect) AddColumn(table, definition string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " ADD COLUMN " + definition
+}
+
+// RenameColumn returns the statement that renames a column of a table
+//
+// SQLite supports it since version 3.25
+func (dialect SQLiteDialect) RenameColumn(table, column, newName string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " RENAME COLUMN " + dialect.Quote(column) + " TO " + dialect.Quote(newName)
+}
+
+// DropColumn returns the statement that removes a column from a table
+//
+// SQLite supports it since version 3.35
+func (dialect SQLiteDialect) DropColumn(table, column string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " DROP COLUMN " + dialect.Quote(column)
+}
+
+// Upsert returns the statement that inserts a row in a table, or updates the existing row that conflicts with it
+//
+// SQLite uses INSERT ... ON CONFLICT, since version 3.24
+func (dialect SQLiteDialect) Upsert(table string, columns, conflict, update []string) (string, error) {
+	return onConflict(dialect, table, columns, conflict, update)
+}
+
+// InsertReturning returns the statement that inserts a row in a table and returns the given columns of the inserted row
+//
+// SQLite (3.35+) uses INSERT ... RETURNING
+func (dialect SQLiteDialect) InsertReturning(table string, columns, returning []string) string {
+	return insertClause(dialect, table, columns) + " RETURNING " + quoteColumns(dialect, returning, "")
+}
+
+// MaxInsertRows returns the maximum number of rows an INSERT statement can insert at once
+//
+// SQLite accepts up to 999 parameters per statement before version 3.32
+func (dialect SQLiteDialect) MaxInsertRows(columns int) int {
+	return maxInsertRows(999, columns)
+}
+
+// CopyIn returns an empty string, SQLite cannot copy rows in bulk
+func (dialect SQLiteDialect) CopyIn(table string, columns []string) string {
+	return ""
+}
+
+// TablesQuery returns the query that lists the tables of the main database
+//
+// implements the Introspector interface
+func (dialect SQLiteDialect) TablesQuery() string {
+	return "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name"
+}
+
+// DescribeQueries returns the queries that describe a table
+//
+// SQLite does not name foreign keys, their name is their id.
+//
+// implements the Introspector interface
+func (dialect SQLiteDialect) DescribeQueries(table string) (CatalogQueries, []interface{}) {
+	master, schema, parms := "sqlite_master", "", []interface{}{table}
+	if namespace, name := splitTable(table); len(namespace) > 0 {
+		master, schema, parms = dialect.Quote(namespace)+".sqlite_master", ", ?2", []interface{}{name, namespace}
+	}
+	return CatalogQueries{
+		Columns:    fmt.Sprintf(`SELECT name, upper(type), "notnull" = 0, dflt_value FROM pragma_table_info(?1%s) ORDER BY cid`, schema),
+		PrimaryKey: fmt.Sprintf(`SELECT name FROM pragma_table_info(?1%s) WHERE pk > 0 ORDER BY pk`, schema),
+		Indexes: fmt.Sprintf(`SELECT il.name, ii.name, il."unique", CASE WHEN il.partial = 1 THEN trim(substr(m.sql, instr(upper(m.sql), ' WHERE ') + 7)) ELSE '' END `+
+			`FROM pragma_index_list(?1%s) il JOIN pragma_index_info(il.name%s) ii LEFT JOIN %s m ON m.type = 'index' AND m.name = il.name `+
+			`WHERE il.origin = 'c' ORDER BY il.name, ii.seqno`, schema, schema, master),
+		ForeignKeys: fmt.Sprintf(`SELECT CAST(id AS TEXT), "from", "table", "to", on_delete, on_update FROM pragma_foreign_key_list(?1%s) ORDER BY id, seq`, schema),
+	}, parms
+}
@@ -0,0 +1,218 @@
+package sql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// SQLServerDialect is the Dialect for Microsoft SQL Server databases
+type SQLServerDialect struct{}
+
+var sqlserverTypes = sqlTypes{
+	UUID:   "UNIQUEIDENTIFIER",
+	Time:   "DATETIME2",
+	Bool:   "BIT",
+	Float:  "FLOAT",
+	String: "NVARCHAR(80)",
+	Int:    "INT",
+	BigInt: "BIGINT",
+}
+
+// Name returns the name of this Dialect
+func (dialect SQLServerDialect) Name() string {
+	return "sqlserver"
+}
+
+// Placeholder returns the placeholder for the parameter at the given position (starting at 1)
+//
+// SQL Server uses @p1, @p2, ...
+func (dialect SQLServerDialect) Placeholder(position int) string {
+	return fmt.Sprintf("@p%d", position)
+}
+
+// Quote quotes the given identifier if it needs to be (reserved word, special characters)
+//
+// SQL Server quotes identifiers with square brackets
+func (dialect SQLServerDialect) Quote(identifier string) string {
+	return quoteIdentifier(identifier, "[", "]")
+}
+
+// SQLType returns the SQL type that matches the given GO type
+func (dialect SQLServerDialect) SQLType(name string, t reflect.Type) (string, error) {
+	return sqlserverTypes.get(name, t)
+}
+
+// Savepoint returns the statement that creates a savepoint in the current transaction
+func (dialect SQLServerDialect) Savepoint(name string) string {
+	return "SAVE TRANSACTION " + dialect.Quote(name)
+}
+
+// ReleaseSavepoint returns an empty string as SQL Server does not release savepoints
+func (dialect SQLServerDialect) ReleaseSavepoint(name string) string {
+	return ""
+}
+
+// RollbackToSavepoint returns the statement that rolls back the current transaction to a savepoint
+func (dialect SQLServerDialect) RollbackToSavepoint(name string) string {
+	return "ROLLBACK TRANSACTION " + dialect.Quote(name)
+}
+
+// AutoIncrement returns the clause that makes a column generate its values automatically
+//
+// SQL Server uses identity columns
+func (dialect SQLServerDialect) AutoIncrement(primaryKey bool) (string, error) {
+	return "IDENTITY(1,1)", nil
+}
+
+// CreateIndex returns the statement that creates an index on a table
+//
+// SQL Server supports partial indexes as filtered indexes
+func (dialect SQLServerDialect) CreateIndex(table string, index Index) (string, error) {
+	return createIndex(dialect, table, index, true)
+}
+
+// Paginate returns the ORDER BY, OFFSET, and FETCH clauses of a SELECT statement, or an empty string
+//
+// SQL Server does not support NULLS FIRST/LAST, which are emulated, and needs an ORDER BY to page the results
+func (dialect SQLServerDialect) Paginate(sorts []Sort, limit, offset int, parms []interface{}) (string, []interface{}) {
+	order := orderBy(dialect, sorts, false)
+	if limit <= 0 && offset <= 0 {
+		return order, parms
+	}
+	if len(order) == 0 {
+		order = "ORDER BY (SELECT NULL)"
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	parms = append(parms, offset)
+	clause := fmt.Sprintf("%s OFFSET %s ROWS", order, dialect.Placeholder(len(parms)))
+	if limit > 0 {
+		parms = append(parms, limit)
+		clause = clause + fmt.Sprintf(" FETCH NEXT %s ROWS ONLY", dialect.Placeholder(len(parms)))
+	}
+	return clause, parms
+}
+
+// TransactionalDDL tells if the statements that change the schema (CREATE TABLE, ALTER TABLE, etc) can run in a transaction
+func (dialect SQLServerDialect) TransactionalDDL() bool {
+	return true
+}
+
+// CountsMatchedRows tells if the rows affected by an UPDATE are the rows it matched, and not only the rows it changed
+func (dialect SQLServerDialect) CountsMatchedRows() bool {
+	return true
+}
+
+// AddColumn returns the statement that adds a column to a table
+//
+// SQL Server does not use the COLUMN keyword
+func (dialect SQLServerDialect) AddColumn(table, definition string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " ADD " + definition
+}
+
+// RenameColumn returns the statement that renames a column of a table
+//
+// SQL Server renames columns with the sp_rename procedure
+func (dialect SQLServerDialect) RenameColumn(table, column, newName string) string {
+	return fmt.Sprintf("EXEC sp_rename '%s.%s', '%s', 'COLUMN'", strings.ReplaceAll(table, "'", "''"), strings.ReplaceAll(column, "'", "''"), strings.ReplaceAll(newName, "'", "''"))
+}
+
+// DropColumn returns the statement that removes a column from a table
+func (dialect SQLServerDialect) DropColumn(table, column string) string {
+	return "ALTER TABLE " + dialect.Quote(table) + " DROP COLUMN " + dialect.Quote(column)
+}
+
+// Upsert returns the statement that inserts a row in a table, or updates the existing row that conflicts with it
+//
+// SQL Server uses MERGE, holding a lock on the matched range so concurrent upserts do not race.
+// The conflict columns are matched against the source row, they must be among the inserted columns
+func (dialect SQLServerDialect) Upsert(table string, columns, conflict, update []string) (string, error) {
+	if len(conflict) == 0 {
+		return "", errors.ArgumentMissing.With("conflict").WithStack()
+	}
+	quoted := make([]string, len(columns))
+	values := make([]string, len(columns))
+	sources := make([]string, len(columns))
+	inserted := map[string]bool{}
+	for i, column := range columns {
+		quoted[i] = dialect.Quote(column)
+		values[i] = dialect.Placeholder(i + 1)
+		sources[i] = "source." + dialect.Quote(column)
+		inserted[column] = true
+	}
+	matches := make([]string, len(conflict))
+	for i, column := range conflict {
+		if !inserted[column] {
+			return "", errors.ArgumentInvalid.With("conflict", column).WithStack()
+		}
+		matches[i] = fmt.Sprintf("target.%s = source.%s", dialect.Quote(column), dialect.Quote(column))
+	}
+	statement := strings.Builder{}
+	statement.WriteString(fmt.Sprintf("MERGE INTO %s WITH (HOLDLOCK) AS target USING (VALUES (%s)) AS source (%s) ON %s",
+		dialect.Quote(table), strings.Join(values, ", "), strings.Join(quoted, ", "), strings.Join(matches, " AND ")))
+	if len(update) > 0 {
+		sets := make([]string, len(update))
+		for i, column := range update {
+			sets[i] = fmt.Sprintf("target.%s = source.%s", dialect.Quote(column), dialect.Quote(column))
+		}
+		statement.WriteString(" WHEN MATCHED THEN UPDATE SET " + strings.Join(sets, ", "))
+	}
+	statement.WriteString(fmt.Sprintf(" WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s);", strings.Join(quoted, ", "), strings.Join(sources, ", ")))
+	return statement.String(), nil
+}
+
+// InsertReturning returns the statement that inserts a row in a table and returns the given columns of the inserted row
+//
+// SQL Server uses INSERT ... OUTPUT INSERTED
+func (dialect SQLServerDialect) InsertReturning(table string, columns, returning []string) string {
+	values := make([]string, len(columns))
+	for i := range columns {
+		values[i] = dialect.Placeholder(i + 1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) OUTPUT %s VALUES (%s)", dialect.Quote(table), quoteColumns(dialect, columns, ""), quoteColumns(dialect, returning, "INSERTED."), strings.Join(values, ", "))
+}
+
+// MaxInsertRows returns the maximum number of rows an INSERT statement can insert at once
+//
+// SQL Server accepts up to 2100 parameters and 1000 rows per statement
+func (dialect SQLServerDialect) MaxInsertRows(columns int) int {
+	if rows := maxInsertRows(2100, columns); rows < 1000 {
+		return rows
+	}
+	return 1000
+}
+
+// CopyIn returns an empty string, the bulk copy of SQL Server depends on its driver
+func (dialect SQLServerDialect) CopyIn(table string, columns []string) string {
+	return ""
+}
+
+// TablesQuery returns the query that lists the tables of the default schema
+//
+// implements the Introspector interface
+func (dialect SQLServerDialect) TablesQuery() string {
+	return "SELECT name FROM sys.tables WHERE schema_id = SCHEMA_ID() ORDER BY name"
+}
+
+// DescribeQueries returns the queries that describe a table
+//
+// implements the Introspector interface
+func (dialect SQLServerDialect) DescribeQueries(table string) (CatalogQueries, []interface{}) {
+	return CatalogQueries{
+		Columns: "SELECT column_name, CASE WHEN character_maximum_length IS NULL THEN upper(data_type) WHEN character_maximum_length = -1 THEN upper(data_type) + '(MAX)' " +
+			"ELSE upper(data_type) + '(' + CAST(character_maximum_length AS VARCHAR(10)) + ')' END, CASE WHEN is_nullable = 'YES' THEN 1 ELSE 0 END, column_default " +
+			"FROM information_schema.columns WHERE table_schema = COALESCE(PARSENAME(@p1, 2), SCHEMA_NAME()) AND table_name = PARSENAME(@p1, 1) ORDER BY ordinal_position",
+		PrimaryKey: "SELECT COL_NAME(ic.object_id, ic.column_id) FROM sys.indexes i JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id " +
+			"WHERE i.object_id = OBJECT_ID(@p1) AND i.is_primary_key = 1 ORDER BY ic.key_ordinal",
+		Indexes: "SELECT i.name, COL_NAME(ic.object_id, ic.column_id), i.is_unique, COALESCE(i.filter_definition, '') FROM sys.indexes i " +
+			"JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id " +
+			"WHERE i.object_id = OBJECT_ID(@p1) AND i.is_primary_key = 0 AND i.name IS NOT NULL AND ic.is_included_column = 0 ORDER BY i.name, ic.key_ordinal",
+		ForeignKeys: "SELECT fk.name, COL_NAME(fkc.parent_object_id, fkc.parent_column_id), OBJECT_NAME(fkc.referenced_object_id), COL_NAME(fkc.referenced_object_id, fkc.referenced_column_id), " +
+			"fk.delete_referential_action_desc, fk.update_referential_action_desc FROM sys.foreign_keys fk JOIN sys.foreign_key_columns fkc ON fkc.constraint_object_id = fk.object_id " +
+			"WHERE fk.parent_object_id = OBJECT_ID(@p1) ORDER BY fk.name, fkc.constraint_column_id",
+	}, []interface{}{table}
+}
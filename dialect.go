@@ -0,0 +1,269 @@
+package sql
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/gildas/go-errors"
+)
+
+// Dialect describes how a database engine speaks SQL
+//
+// A Dialect controls the placeholder syntax, the identifier quoting, and the mapping of GO types to SQL types
+type Dialect interface {
+	// Name returns the name of this Dialect
+	Name() string
+
+	// Placeholder returns the placeholder for the parameter at the given position (starting at 1)
+	Placeholder(position int) string
+
+	// Quote quotes the given identifier if it needs to be (reserved word, special characters)
+	Quote(identifier string) string
+
+	// SQLType returns the SQL type that matches the given GO type
+	SQLType(name string, t reflect.Type) (string, error)
+
+	// Savepoint returns the statement that creates a savepoint in the current transaction
+	Savepoint(name string) string
+
+	// ReleaseSavepoint returns the statement that releases a savepoint, or an empty string if the database does not release savepoints
+	ReleaseSavepoint(name string) string
+
+	// RollbackToSavepoint returns the statement that rolls back the current transaction to a savepoint
+	RollbackToSavepoint(name string) string
+
+	// AutoIncrement returns the clause that makes a column generate its values automatically
+	AutoIncrement(primaryKey bool) (string, error)
+
+	// CreateIndex returns the statement that creates an index on a table
+	CreateIndex(table string, index Index) (string, error)
+
+	// Paginate returns the ORDER BY, LIMIT, and OFFSET clauses of a SELECT statement, or an empty string
+	//
+	// A limit or an offset of 0 means no limit or no offset.
+	// The limit and the offset are parameters of the clauses, they are appended to parms and numbered after them
+	// so the statement does not change from page to page
+	Paginate(sorts []Sort, limit, offset int, parms []interface{}) (string, []interface{})
+
+	// TransactionalDDL tells if the statements that change the schema (CREATE TABLE, ALTER TABLE, etc) can run in a transaction
+	TransactionalDDL() bool
+
+	// CountsMatchedRows tells if the rows affected by an UPDATE are the rows it matched, and not only the rows it changed
+	CountsMatchedRows() bool
+
+	// AddColumn returns the statement that adds a column to a table, given the definition of the column
+	AddColumn(table, definition string) string
+
+	// RenameColumn returns the statement that renames a column of a table
+	RenameColumn(table, column, newName string) string
+
+	// DropColumn returns the statement that removes a column from a table
+	DropColumn(table, column string) string
+
+	// Upsert returns the statement that inserts a row in a table, or updates the existing row that conflicts with it on the conflict columns
+	//
+	// The parameters of the statement are the values of the columns, in order. If update is empty, the existing row is kept.
+	// The conflict columns are among the columns
+	Upsert(table string, columns, conflict, update []string) (string, error)
+
+	// InsertReturning returns the statement that inserts a row in a table and returns the given columns of the inserted row
+	//
+	// The parameters of the statement are the values of the columns, in order.
+	// If the database cannot return the inserted row, an empty string is returned and the generated key is read with LastInsertId
+	InsertReturning(table string, columns, returning []string) string
+
+	// MaxInsertRows returns the maximum number of rows an INSERT statement can insert at once, given the number of columns of the rows
+	MaxInsertRows(columns int) int
+
+	// CopyIn returns the statement that copies rows into a table in bulk, or an empty string if the database cannot copy rows
+	//
+	// The statement is prepared in a transaction, executed once per row with the values of the columns, then once without parameters
+	CopyIn(table string, columns []string) string
+}
+
+var (
+	dialectsMutex sync.RWMutex
+	dialects      = map[string]Dialect{
+		"postgres":  PostgresDialect{},
+		"pgx":       PostgresDialect{},
+		"ramsql":    PostgresDialect{},
+		"mysql":     MySQLDialect{},
+		"sqlite":    SQLiteDialect{},
+		"sqlite3":   SQLiteDialect{},
+		"mssql":     SQLServerDialect{},
+		"sqlserver": SQLServerDialect{},
+	}
+)
+
+// RegisterDialect registers the Dialect to use with the given driver name
+//
+// If a Dialect was already registered with that driver name, it is replaced
+func RegisterDialect(drivername string, dialect Dialect) {
+	dialectsMutex.Lock()
+	defer dialectsMutex.Unlock()
+	dialects[drivername] = dialect
+}
+
+// GetDialect returns the Dialect registered with the given driver name
+//
+// If no Dialect was registered with that driver name, the PostgreSQL Dialect is returned
+func GetDialect(drivername string) Dialect {
+	dialectsMutex.RLock()
+	defer dialectsMutex.RUnlock()
+	if dialect, found := dialects[drivername]; found {
+		return dialect
+	}
+	return PostgresDialect{}
+}
+
+// getDialect returns the Dialect of the given DB, or the PostgreSQL Dialect if there is none
+func getDialect(db *DB) Dialect {
+	if db == nil || db.Dialect == nil {
+		return PostgresDialect{}
+	}
+	return db.Dialect
+}
+
+// private tools shared by the Dialects
+
+var simpleIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+var reservedWords = map[string]bool{
+	"all": true, "and": true, "as": true, "asc": true, "between": true, "by": true,
+	"case": true, "check": true, "column": true, "constraint": true, "create": true,
+	"default": true, "delete": true, "desc": true, "distinct": true, "drop": true,
+	"else": true, "end": true, "foreign": true, "from": true, "group": true,
+	"having": true, "in": true, "index": true, "insert": true, "into": true, "is": true,
+	"join": true, "key": true, "like": true, "limit": true, "not": true, "null": true,
+	"offset": true, "on": true, "or": true, "order": true, "primary": true,
+	"references": true, "select": true, "set": true, "table": true, "then": true,
+	"to": true, "union": true, "unique": true, "update": true, "user": true,
+	"using": true, "values": true, "when": true, "where": true,
+}
+
+// splitTable splits a table name qualified with a namespace (e.g.: sales.orders), the namespace is empty if the name is not qualified
+func splitTable(table string) (namespace, name string) {
+	if i := strings.LastIndex(table, "."); i >= 0 {
+		return table[:i], table[i+1:]
+	}
+	return "", table
+}
+
+// quoteIdentifier quotes each part of a (possibly qualified) identifier that needs it
+func quoteIdentifier(identifier, open, close string) string {
+	parts := strings.Split(identifier, ".")
+	for i, part := range parts {
+		if simpleIdentifier.MatchString(part) && !reservedWords[strings.ToLower(part)] {
+			continue
+		}
+		parts[i] = open + strings.ReplaceAll(part, close, close+close) + close
+	}
+	return strings.Join(parts, ".")
+}
+
+// orderBy builds the ORDER BY clause of a SELECT statement
+//
+// If the database does not support NULLS FIRST/LAST, nulls must be false and the order of NULL values is emulated
+func orderBy(dialect Dialect, sorts []Sort, nulls bool) string {
+	if len(sorts) == 0 {
+		return ""
+	}
+	keys := []string{}
+	for _, sort := range sorts {
+		column := dialect.Quote(sort.Column)
+		direction := "ASC"
+		if sort.Descending {
+			direction = "DESC"
+		}
+		switch {
+		case sort.Nulls == NullsDefault:
+			keys = append(keys, column+" "+direction)
+		case nulls && sort.Nulls == NullsFirst:
+			keys = append(keys, column+" "+direction+" NULLS FIRST")
+		case nulls && sort.Nulls == NullsLast:
+			keys = append(keys, column+" "+direction+" NULLS LAST")
+		case sort.Nulls == NullsFirst:
+			keys = append(keys, "CASE WHEN "+column+" IS NULL THEN 0 ELSE 1 END", column+" "+direction)
+		case sort.Nulls == NullsLast:
+			keys = append(keys, "CASE WHEN "+column+" IS NULL THEN 1 ELSE 0 END", column+" "+direction)
+		}
+	}
+	return "ORDER BY " + strings.Join(keys, ", ")
+}
+
+// paginate builds the ORDER BY, LIMIT, and OFFSET clauses of a SELECT statement
+//
+// noLimit is the LIMIT value to use when there is an offset but no limit (empty if LIMIT can be omitted)
+func paginate(dialect Dialect, sorts []Sort, nulls bool, limit, offset int, noLimit string, parms []interface{}) (string, []interface{}) {
+	clauses := []string{}
+	if order := orderBy(dialect, sorts, nulls); len(order) > 0 {
+		clauses = append(clauses, order)
+	}
+	if limit > 0 {
+		parms = append(parms, limit)
+		clauses = append(clauses, "LIMIT "+dialect.Placeholder(len(parms)))
+	} else if offset > 0 && len(noLimit) > 0 {
+		clauses = append(clauses, "LIMIT "+noLimit)
+	}
+	if offset > 0 {
+		parms = append(parms, offset)
+		clauses = append(clauses, "OFFSET "+dialect.Placeholder(len(parms)))
+	}
+	return strings.Join(clauses, " "), parms
+}
+
+// maxInsertRows returns the number of rows of the given number of columns that fit in the given number of parameters
+func maxInsertRows(parameters, columns int) int {
+	if columns <= 1 {
+		return parameters
+	}
+	return parameters / columns
+}
+
+// sqlTypes contains the SQL types a Dialect uses for the GO types we support
+type sqlTypes struct {
+	UUID   string
+	Time   string
+	Bool   string
+	Float  string
+	String string
+	Int    string
+	BigInt string
+}
+
+func (types sqlTypes) get(name string, t reflect.Type) (string, error) {
+	switch t.Kind() {
+	case reflect.Array, reflect.Slice:
+		switch t.Name() {
+		case "UUID":
+			return types.UUID, nil
+		default:
+			return "", errors.ArgumentInvalid.With("typeof", name).WithStack()
+		}
+	case reflect.Struct:
+		switch t.Name() {
+		case "Time":
+			return types.Time, nil
+		default:
+			return "", errors.ArgumentInvalid.With("typeof", name).WithStack()
+		}
+	case reflect.Bool:
+		return types.Bool, nil
+	case reflect.Float32, reflect.Float64:
+		return types.Float, nil
+	case reflect.String:
+		return types.String, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32:
+		return types.Int, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return types.Int, nil
+	case reflect.Int64, reflect.Uint32, reflect.Uint64:
+		return types.BigInt, nil
+	case reflect.Ptr:
+		return types.get(name, t.Elem())
+	default:
+		return "", errors.ArgumentInvalid.With("typeof", name).WithStack()
+	}
+}
@@ -0,0 +1,227 @@
+package sql_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/suite"
+)
+
+type DialectSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+func TestDialectSuite(t *testing.T) {
+	suite.Run(t, new(DialectSuite))
+}
+
+type dialectGolden struct {
+	Dialect sql.Dialect
+	Select  string
+	Insert  string
+	Update  string
+	Delete  string
+	In      string
+	Page    string
+	Offset  string
+	Rename  string
+	Drop    string
+	Types   []string
+}
+
+var dialectGoldens = []dialectGolden{
+	{
+		Dialect: sql.PostgresDialect{},
+		Select:  `SELECT id, "order" FROM person WHERE age > $1`,
+		Insert:  `INSERT INTO "user" (age) VALUES ($1)`,
+		Update:  `UPDATE person SET age = $1 WHERE id = $2`,
+		Delete:  `DELETE FROM person WHERE age > $1`,
+		In:      `id IN ($1, $2, $3)`,
+		Page:    `ORDER BY age DESC NULLS LAST, name ASC LIMIT $1 OFFSET $2`,
+		Offset:  `OFFSET $1`,
+		Rename:  `ALTER TABLE person RENAME COLUMN name TO "user"`,
+		Drop:    `ALTER TABLE person DROP COLUMN "order"`,
+		Types:   []string{"UUID", "TIMESTAMP", "BOOL", "FLOAT8", "VARCHAR(80)", "INT", "BIGINT"},
+	},
+	{
+		Dialect: sql.MySQLDialect{},
+		Select:  "SELECT id, `order` FROM person WHERE age > ?",
+		Insert:  "INSERT INTO `user` (age) VALUES (?)",
+		Update:  "UPDATE person SET age = ? WHERE id = ?",
+		Delete:  "DELETE FROM person WHERE age > ?",
+		In:      "id IN (?, ?, ?)",
+		Page:    `ORDER BY CASE WHEN age IS NULL THEN 1 ELSE 0 END, age DESC, name ASC LIMIT ? OFFSET ?`,
+		Offset:  `LIMIT 18446744073709551615 OFFSET ?`,
+		Rename:  "ALTER TABLE person RENAME COLUMN name TO `user`",
+		Drop:    "ALTER TABLE person DROP COLUMN `order`",
+		Types:   []string{"CHAR(36)", "DATETIME(6)", "BOOLEAN", "DOUBLE", "VARCHAR(80)", "INT", "BIGINT"},
+	},
+	{
+		Dialect: sql.SQLiteDialect{},
+		Select:  `SELECT id, "order" FROM person WHERE age > ?`,
+		Insert:  `INSERT INTO "user" (age) VALUES (?)`,
+		Update:  `UPDATE person SET age = ? WHERE id = ?`,
+		Delete:  `DELETE FROM person WHERE age > ?`,
+		In:      `id IN (?, ?, ?)`,
+		Page:    `ORDER BY age DESC NULLS LAST, name ASC LIMIT ? OFFSET ?`,
+		Offset:  `LIMIT -1 OFFSET ?`,
+		Rename:  `ALTER TABLE person RENAME COLUMN name TO "user"`,
+		Drop:    `ALTER TABLE person DROP COLUMN "order"`,
+		Types:   []string{"TEXT", "TIMESTAMP", "BOOLEAN", "REAL", "VARCHAR(80)", "INTEGER", "INTEGER"},
+	},
+	{
+		Dialect: sql.SQLServerDialect{},
+		Select:  `SELECT id, [order] FROM person WHERE age > @p1`,
+		Insert:  `INSERT INTO [user] (age) VALUES (@p1)`,
+		Update:  `UPDATE person SET age = @p1 WHERE id = @p2`,
+		Delete:  `DELETE FROM person WHERE age > @p1`,
+		In:      `id IN (@p1, @p2, @p3)`,
+		Page:    `ORDER BY CASE WHEN age IS NULL THEN 1 ELSE 0 END, age DESC, name ASC OFFSET @p1 ROWS FETCH NEXT @p2 ROWS ONLY`,
+		Offset:  `ORDER BY (SELECT NULL) OFFSET @p1 ROWS`,
+		Rename:  `EXEC sp_rename 'person.name', 'user', 'COLUMN'`,
+		Drop:    `ALTER TABLE person DROP COLUMN [order]`,
+		Types:   []string{"UNIQUEIDENTIFIER", "DATETIME2", "BIT", "FLOAT", "NVARCHAR(80)", "INT", "BIGINT"},
+	},
+}
+
+func (suite *DialectSuite) TestCanGetDialectFromDriverName() {
+	suite.Assert().Equal("postgres", sql.GetDialect("postgres").Name())
+	suite.Assert().Equal("postgres", sql.GetDialect("ramsql").Name())
+	suite.Assert().Equal("mysql", sql.GetDialect("mysql").Name())
+	suite.Assert().Equal("sqlite", sql.GetDialect("sqlite3").Name())
+	suite.Assert().Equal("sqlserver", sql.GetDialect("sqlserver").Name())
+	suite.Assert().Equal("postgres", sql.GetDialect("unknown").Name(), "Unknown drivers should use the PostgreSQL Dialect")
+}
+
+func (suite *DialectSuite) TestCanRegisterDialect() {
+	sql.RegisterDialect("mymysql", sql.MySQLDialect{})
+	suite.Assert().Equal("mysql", sql.GetDialect("mymysql").Name())
+}
+
+func (suite *DialectSuite) TestOpenShouldPickDialect() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err)
+	defer db.Close()
+	suite.Require().NotNil(db.Dialect)
+	suite.Assert().Equal("postgres", db.Dialect.Name())
+}
+
+func (suite *DialectSuite) TestCanQuoteIdentifiers() {
+	suite.Assert().Equal(`person`, sql.PostgresDialect{}.Quote("person"))
+	suite.Assert().Equal(`"user"`, sql.PostgresDialect{}.Quote("user"))
+	suite.Assert().Equal(`"User"`, sql.PostgresDialect{}.Quote("User"), "Reserved words should be quoted whatever their case")
+	suite.Assert().Equal(`Name`, sql.PostgresDialect{}.Quote("Name"), "Mixed case identifiers should not be quoted")
+	suite.Assert().Equal(`"First Name"`, sql.PostgresDialect{}.Quote("First Name"))
+	suite.Assert().Equal(`public."order"`, sql.PostgresDialect{}.Quote("public.order"))
+	suite.Assert().Equal("`we``ird`", sql.MySQLDialect{}.Quote("we`ird"))
+	suite.Assert().Equal(`[we]]ird]`, sql.SQLServerDialect{}.Quote("we]ird"))
+}
+
+func (suite *DialectSuite) TestCanBuildGoldenStatements() {
+	for _, golden := range dialectGoldens {
+		db := &sql.DB{Dialect: golden.Dialect, Logger: suite.Logger}
+		name := golden.Dialect.Name()
+
+		stmt, parms := sql.SelectStatement{}.With(db).Build("person", []string{"id", "order"}, sql.Queries{}.Add("age", sql.QueryGreater, 18))
+		suite.Assert().Equal(golden.Select, stmt, "Select for %s", name)
+		suite.Assert().Equal([]interface{}{18}, parms, "Select for %s", name)
+
+		stmt, parms = sql.InsertStatement{}.With(db).Build("user", nil, sql.Queries{}.Add("age", sql.QuerySet, 18))
+		suite.Assert().Equal(golden.Insert, stmt, "Insert for %s", name)
+		suite.Assert().Equal([]interface{}{18}, parms, "Insert for %s", name)
+
+		stmt, parms = sql.UpdateStatement{}.With(db).Build("person", nil, sql.Queries{}.Add("id", "1234").Add("age", sql.QuerySet, 25))
+		suite.Assert().Equal(golden.Update, stmt, "Update for %s", name)
+		suite.Assert().Equal([]interface{}{25, "1234"}, parms, "Update for %s", name)
+
+		stmt, parms = sql.DeleteStatement{}.With(db).Build("person", nil, sql.Queries{}.Add("age", sql.QueryGreater, 50))
+		suite.Assert().Equal(golden.Delete, stmt, "Delete for %s", name)
+		suite.Assert().Equal([]interface{}{50}, parms, "Delete for %s", name)
+
+		where, parms := sql.Queries{}.Add("id", "1", "2", "3").WhereClauseWith(golden.Dialect, []interface{}{})
+		suite.Assert().Equal(golden.In, where, "Where Clause for %s", name)
+		suite.Assert().Len(parms, 3, "Where Clause for %s", name)
+	}
+}
+
+func (suite *DialectSuite) TestCanBuildGoldenPagination() {
+	for _, golden := range dialectGoldens {
+		db := &sql.DB{Dialect: golden.Dialect, Logger: suite.Logger}
+		name := golden.Dialect.Name()
+
+		stmt, parms := sql.SelectStatement{}.With(db).Build("person", []string{"id"}, sql.Queries{}.OrderBy("-age NULLS LAST", "name").Limit(10).Offset(20))
+		suite.Assert().Equal("SELECT id FROM person "+golden.Page, stmt, "Page for %s", name)
+		suite.Assert().ElementsMatch([]interface{}{10, 20}, parms, "Page for %s", name)
+
+		clause, parms := sql.Queries{}.Offset(5).OrderClauseWith(golden.Dialect, []interface{}{})
+		suite.Assert().Equal(golden.Offset, clause, "Offset for %s", name)
+		suite.Assert().Equal([]interface{}{5}, parms, "Offset for %s", name)
+		clause, _ = sql.Queries{}.OrderClauseWith(golden.Dialect, []interface{}{})
+		suite.Assert().Empty(clause, "No pagination for %s", name)
+	}
+}
+
+func (suite *DialectSuite) TestCanBuildGoldenColumnChanges() {
+	for _, golden := range dialectGoldens {
+		name := golden.Dialect.Name()
+		suite.Assert().Equal(golden.Rename, golden.Dialect.RenameColumn("person", "name", "user"), "Rename for %s", name)
+		suite.Assert().Equal(golden.Drop, golden.Dialect.DropColumn("person", "order"), "Drop for %s", name)
+	}
+}
+
+func (suite *DialectSuite) TestCanMapTypes() {
+	pointy := int64(12)
+	samples := []interface{}{uuid.New(), time.Now(), true, 3.1415, "Doe", 18, &pointy}
+	for _, golden := range dialectGoldens {
+		for i, sample := range samples {
+			sqltype, err := golden.Dialect.SQLType("sample", reflect.TypeOf(sample))
+			suite.Require().Nil(err, "Failed to map %T for %s", sample, golden.Dialect.Name())
+			suite.Assert().Equal(golden.Types[i], sqltype, "Type of %T for %s", sample, golden.Dialect.Name())
+		}
+		_, err := golden.Dialect.SQLType("sample", reflect.TypeOf(complex64(1)))
+		suite.Assert().NotNil(err, "complex64 should not be supported by %s", golden.Dialect.Name())
+	}
+}
+
+// Suite Tools
+
+func (suite *DialectSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *DialectSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *DialectSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *DialectSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
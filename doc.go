@@ -34,6 +34,13 @@ Example:
 		err = db.Insert(Person{"1234", "Doe", "John", 34, 314})
 		err = db.Insert(Person{"4567", "Doe", "Jane", 15, 314})
 
+		// Insert a pointer to get the values generated by the database (autoincrement keys, defaults)
+		ticket := Ticket{Title: "Broken"}
+		err = db.Insert(&ticket) // ticket.ID is now set
+
+		// Insert many rows by batches, in one transaction
+		err = db.InsertMany([]Person{{"7890", "Doe", "Jim", 12, 314}, {"7891", "Doe", "Jill", 10, 314}}, sql.InsertManyOptions{})
+
 		// Find data!
 		results, err := db.FindAll(Person{}, sql.Queries{}.Add("lastname", "Doe"))
 
@@ -47,9 +54,40 @@ Example:
 		result, err := db.Find(Person{}, sql.Queries{}.Add("lastname", "Doe").Add("age", sql.QueryGreater, 18))
 		fmt.Printf("%s %s, age=%d\n", person.Lastname, person.Firstname, person.Age)
 
+		// Find data with combined conditions: lastname = 'Doe' AND (age < 18 OR NOT firstname = 'John')
+		results, err = db.FindAll(Person{}, sql.Queries{}.Add("lastname", "Doe").Match(sql.Or(sql.Where("age", sql.QueryLesser, 18), sql.Not(sql.Where("firstname", "John")))))
+
+		// Find sorted and paged data (also read from URLs like ?sort=-age,lastname&limit=10&offset=20)
+		results, err = db.FindAll(Person{}, sql.Queries{}.Add("lastname", "Doe").OrderBy("-age", "firstname").Limit(10).Offset(20))
+
+		// Find pages of data with keyset pagination (also read from URLs like ?sort=-age&limit=10&cursor=...)
+		page, err := db.FindPage(Person{}, sql.Queries{}.Add("lastname", "Doe").OrderBy("-age").Limit(10))
+		page, err = db.FindPage(Person{}, sql.Queries{}.Add("lastname", "Doe").OrderBy("-age").Limit(10).After(page.Next))
+
+		// Find data directly into a slice of Person (or of *Person)
+		people := []Person{}
+		err = db.Select(&people, sql.Queries{}.Add("lastname", "Doe"))
+
+		// Find Single data directly into a Person
+		person := Person{}
+		err = db.Get(&person, sql.Queries{}.Add("id", "1234"))
+
 		// Update data
 		err := suite.DB.UpdateAll(Person{}, sql.Queries{}.Add("age", 18).Add("age", sql.QuerySet, 25))
 
+		// Tell how many rows were updated, fail with errors.NotFound if no row matched
+		result, err := db.UpdateAllAffected(Person{}, sql.Queries{}.Add("id", "1234").Add("age", sql.QuerySet, 36), sql.AffectOptions{MustMatch: true})
+		fmt.Println(result.RowsAffected)
+
+		// Update or Delete a single row, matched on its key (errors.NotFound if there is no such row)
+		person.Age = 36
+		err = db.Update(person)
+		err = db.Delete(person)
+
+		// Insert or Update a row atomically, the conflict is detected on the key unless told otherwise
+		err = db.Upsert(person, sql.UpsertOptions{})
+		err = db.Upsert(person, sql.UpsertOptions{Conflict: []string{"email"}, Update: []string{"age"}})
+
 		// Delete data
 		err := suite.DB.DeleteAll(Person{}, sql.Queries{}.Add("age", sql.QueryGreater, 50))
 
@@ -84,6 +122,107 @@ Note that for the foreign keys to work,
 - the target `struct` must implement the database/sql Scanner (https://pkg.go.dev/database/sql?tab=doc#Scanner) interface,
 - the target `struct` key must be a uuid.UUID, string, or int (any type of int)
 
+CreateTable adds a FOREIGN KEY ... REFERENCES constraint for each foreign key.
+The tag options ondelete=action and onupdate=action take one of cascade, restrict, setnull, setdefault, or noaction.
+When CreateTable is given several schemas, the referenced tables are created first (DeleteTable deletes them last):
+
+	type TeamMember struct {
+		ID      uuid.UUID `sql:"key"`
+		Manager *Manager  `sql:"foreign=ID,ondelete=cascade"`
+	}
+
+	err = db.CreateTable(TeamMember{}, Manager{})
+
+Columns can be constrained with the tag options notnull, null, default=expression, check=expression, and autoincrement (or serial).
+Pointer fields are nullable and the other fields are NOT NULL unless told otherwise:
+
+	type Ticket struct {
+		ID       int        `sql:"key,serial"`
+		Status   string     `sql:"default='open'"`
+		Priority int        `sql:"default=3,check=priority BETWEEN 1 AND 5"`
+		Closed   *time.Time
+	}
+
+Indexes are created with the table from the tag options index, index=name, unique, unique=name, and where=condition.
+Fields with the same index name create a composite index:
+
+	type Account struct {
+		ID     string `sql:"key"`
+		Email  string `sql:"unique"`
+		Tenant string `sql:"index=account_tenant_name"`
+		Name   string `sql:"index=account_tenant_name"`
+		Active bool   `sql:"index=account_active_idx,where=active"`
+	}
+
+By default, tables and columns are named after the lowercase name of their struct and field (OrderLine is stored in orderline).
+A type can choose its table name by implementing the TableNamer interface, the name is used as is.
+The NamingStrategy of the DB changes how all the other tables and columns are named:
+
+	db.Naming = sql.NamingStrategy{SnakeCase: true, PluralTables: true, Namespace: "sales"} // OrderLine => sales.order_lines
+
+The SQL Dialect (placeholders, identifier quoting, and SQL types) is picked from the driver name given to Open.
+PostgreSQL, MySQL, SQLite, and SQL Server are supported out of the box, other drivers can be mapped with RegisterDialect:
+
+	sql.RegisterDialect("mymysql", sql.MySQLDialect{})
+
+The structured operations can also run within a transaction:
+
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		if err := tx.Insert(Person{"1234", "Doe", "John", 34, 314}); err != nil {
+			return err
+		}
+		return db.UpdateAllContext(tx.Context(), Person{}, sql.Queries{}.Add("lastname", "Doe").Add("age", sql.QuerySet, 35))
+	})
+
+The transaction is committed if the func succeeds, and rolled back if it returns an error or panics.
+The context of the transaction (Tx.Context) contains it, so the operations of the DB run with it join the transaction.
+
+A typed Repository can be created for a struct type, its methods are typed and its table and column metadata are cached:
+
+	persons, err := sql.NewRepository[Person](db)
+	people, err := persons.FindAll(context.Background(), sql.Queries{}.Add("lastname", "Doe"))
+	err = persons.Insert(context.Background(), &Person{"1234", "Doe", "John", 34, 314})
+	err = persons.Update(context.Background(), Person{"1234", "Doe", "John", 35, 314})
+	count, err := persons.Count(context.Background(), sql.Queries{}.Add("age", sql.QueryGreater, 18))
+
+Update and Delete (of the Repository, DB, or Tx) match the row on the fields tagged as `sql:"key"`, they return an errors.NotFound if no row matched.
+MySQL counts only the rows an UPDATE changed, so when an UPDATE changed no row, the row is looked for with a SELECT COUNT(*).
+When several fields are tagged as `sql:"key"`, CreateTable creates a composite primary key and the rows are matched on all of them.
+
+AutoMigrate updates the tables of the given types so they match the types: missing tables are created, missing columns and indexes are added.
+The differences that could lose data (removed fields, changed types, etc) are not applied, they are returned instead:
+
+	differences, err := db.AutoMigrate(Person{}, Manager{}, TeamMember{})
+
+The tables of the database can be listed and described (columns, primary key, indexes, and foreign keys) if the Dialect is an Introspector:
+
+	tables, err := db.Tables(context.Background())
+	description, err := db.DescribeTable(context.Background(), "person")
+
+Schema changes can be applied with versioned migrations, their steps are GO funcs or .sql files loaded with LoadMigrations
+(0001_create_persons.up.sql, 0001_create_persons.down.sql).
+The applied migrations are recorded in the schema_migrations table, each migration runs in its own transaction if the Dialect allows it:
+
+	migrations, err := sql.LoadMigrations(os.DirFS("."), "migrations")
+	migrator, err := sql.NewMigrator(db, migrations...)
+	err = migrator.Migrate(context.Background())
+	err = migrator.Rollback(context.Background(), 1)
+
+Migrate, MigrateTo, and Rollback hold the Lock of the Migrator (a leased row of the schema_locks table),
+so when several replicas start at once, only one applies the migrations and the others wait for it.
+Tables can be changed without downtime with the expand/contract helpers AddColumnWithBackfill, RenameColumn, and ChangeColumnType.
+The rows are copied by batches and each phase can be run again if it was interrupted:
+
+	rename := sql.ColumnChange{Table: "person", Column: "name", NewColumn: "fullname", Type: "VARCHAR(80)"}
+	err = db.RenameColumn(ctx, rename, sql.PhaseExpand)   // then deploy the application that writes both columns
+	err = db.RenameColumn(ctx, rename, sql.PhaseContract) // once no instance uses the old column
+
+A Lock can also guard other operations:
+
+	err = sql.NewLock(db, "tables").Do(ctx, func(ctx context.Context) error { return db.CreateTableContext(ctx, Person{}) })
+
+If the lease of the Lock is lost while the func runs, its context is cancelled and Do returns an error.
+
 You can also use the Statement object level of using the Database:
 
 	package main
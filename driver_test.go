@@ -0,0 +1,248 @@
+package sql_test
+
+import (
+	"context"
+	gosql "database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// recorder is a database/sql driver that records the statements it is given instead of running them
+//
+// Each data source name gets its own recording, tests use their name as data source name.
+// Statements affect one row, unless told otherwise with Affect, whose generated id is 1.
+// Queries return no rows, unless a response was given with Respond. Statements and queries fail if told so with Fail.
+// Statements run at once, unless told otherwise with Delay. The parameters of each statement are recorded too
+type recorder struct {
+	sync.Mutex
+	recordings map[string][]string
+	parameters map[string][][]driver.Value
+	prepared   map[string]int
+	responses  map[string]map[string]*recorderRows
+	affected   map[string]map[string]int64
+	failures   map[string]map[string]error
+	delays     map[string]map[string]time.Duration
+}
+
+var recordingDriver = &recorder{recordings: map[string][]string{}, parameters: map[string][][]driver.Value{}, prepared: map[string]int{}, responses: map[string]map[string]*recorderRows{}, affected: map[string]map[string]int64{}, failures: map[string]map[string]error{}, delays: map[string]map[string]time.Duration{}}
+
+func init() {
+	gosql.Register("recorder", recordingDriver)
+}
+
+// Recorded returns the statements recorded for the given data source name
+func Recorded(name string) []string {
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	return append([]string{}, recordingDriver.recordings[name]...)
+}
+
+// Parameters returns the parameters of the statements recorded for the given data source name
+func Parameters(name string) [][]driver.Value {
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	return append([][]driver.Value{}, recordingDriver.parameters[name]...)
+}
+
+// Prepared returns the number of statements prepared for the given data source name
+func Prepared(name string) int {
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	return recordingDriver.prepared[name]
+}
+
+// Respond sets the rows the given query returns for the given data source name
+//
+// The columns are given as "name TYPE"
+func Respond(name, query string, columns []string, rows ...[]driver.Value) {
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	if _, found := recordingDriver.responses[name]; !found {
+		recordingDriver.responses[name] = map[string]*recorderRows{}
+	}
+	recordingDriver.responses[name][query] = &recorderRows{columns: columns, rows: rows}
+}
+
+// Affect sets the number of rows the given statement affects for the given data source name
+func Affect(name, statement string, count int64) {
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	if _, found := recordingDriver.affected[name]; !found {
+		recordingDriver.affected[name] = map[string]int64{}
+	}
+	recordingDriver.affected[name][statement] = count
+}
+
+// Fail sets the error the given statement or query returns for the given data source name, a nil error lets it run again
+func Fail(name, query string, err error) {
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	if _, found := recordingDriver.failures[name]; !found {
+		recordingDriver.failures[name] = map[string]error{}
+	}
+	if err == nil {
+		delete(recordingDriver.failures[name], query)
+		return
+	}
+	recordingDriver.failures[name][query] = err
+}
+
+// Delay sets how long the given statement takes for the given data source name, unless its context is done first
+func Delay(name, statement string, delay time.Duration) {
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	if _, found := recordingDriver.delays[name]; !found {
+		recordingDriver.delays[name] = map[string]time.Duration{}
+	}
+	recordingDriver.delays[name][statement] = delay
+}
+
+func (recorder *recorder) record(name, statement string, args []driver.Value) {
+	recorder.Lock()
+	defer recorder.Unlock()
+	recorder.recordings[name] = append(recorder.recordings[name], statement)
+	recorder.parameters[name] = append(recorder.parameters[name], args)
+}
+
+func (recorder *recorder) Open(name string) (driver.Conn, error) {
+	return &recorderConn{name}, nil
+}
+
+type recorderConn struct {
+	name string
+}
+
+func (conn *recorderConn) Prepare(query string) (driver.Stmt, error) {
+	recordingDriver.Lock()
+	recordingDriver.prepared[conn.name]++
+	recordingDriver.Unlock()
+	return &recorderStmt{conn.name, query}, nil
+}
+
+func (conn *recorderConn) Close() error {
+	return nil
+}
+
+func (conn *recorderConn) Begin() (driver.Tx, error) {
+	recordingDriver.record(conn.name, "BEGIN", nil)
+	return &recorderTx{conn.name}, nil
+}
+
+type recorderTx struct {
+	name string
+}
+
+func (tx *recorderTx) Commit() error {
+	recordingDriver.record(tx.name, "COMMIT", nil)
+	return nil
+}
+
+func (tx *recorderTx) Rollback() error {
+	recordingDriver.record(tx.name, "ROLLBACK", nil)
+	return nil
+}
+
+type recorderStmt struct {
+	name  string
+	query string
+}
+
+func (stmt *recorderStmt) Close() error {
+	return nil
+}
+
+func (stmt *recorderStmt) NumInput() int {
+	return -1
+}
+
+func (stmt *recorderStmt) Exec(args []driver.Value) (driver.Result, error) {
+	recordingDriver.record(stmt.name, stmt.query, args)
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	if err, found := recordingDriver.failures[stmt.name][stmt.query]; found {
+		return nil, err
+	}
+	if count, found := recordingDriver.affected[stmt.name][stmt.query]; found {
+		return recorderResult{count}, nil
+	}
+	return recorderResult{1}, nil
+}
+
+func (stmt *recorderStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	recordingDriver.Lock()
+	delay := recordingDriver.delays[stmt.name][stmt.query]
+	recordingDriver.Unlock()
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	return stmt.Exec(values)
+}
+
+type recorderResult struct {
+	affected int64
+}
+
+func (result recorderResult) LastInsertId() (int64, error) {
+	return 1, nil
+}
+
+func (result recorderResult) RowsAffected() (int64, error) {
+	return result.affected, nil
+}
+
+func (stmt *recorderStmt) Query(args []driver.Value) (driver.Rows, error) {
+	recordingDriver.record(stmt.name, stmt.query, args)
+	recordingDriver.Lock()
+	defer recordingDriver.Unlock()
+	if err, found := recordingDriver.failures[stmt.name][stmt.query]; found {
+		return nil, err
+	}
+	if response, found := recordingDriver.responses[stmt.name][stmt.query]; found {
+		return &recorderRows{columns: response.columns, rows: response.rows}, nil
+	}
+	return &recorderRows{}, nil
+}
+
+type recorderRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (rows *recorderRows) Columns() []string {
+	names := make([]string, len(rows.columns))
+	for i, column := range rows.columns {
+		names[i] = strings.SplitN(column, " ", 2)[0]
+	}
+	return names
+}
+
+func (rows *recorderRows) ColumnTypeDatabaseTypeName(index int) string {
+	if parts := strings.SplitN(rows.columns[index], " ", 2); len(parts) > 1 {
+		return parts[1]
+	}
+	return ""
+}
+
+func (rows *recorderRows) Close() error {
+	return nil
+}
+
+func (rows *recorderRows) Next(dest []driver.Value) error {
+	if len(rows.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, rows.rows[0])
+	rows.rows = rows.rows[1:]
+	return nil
+}
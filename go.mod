@@ -1,6 +1,6 @@
 module github.com/gildas/go-sql
 
-go 1.14
+go 1.18
 
 require (
 	cloud.google.com/go v0.55.0 // indirect
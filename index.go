@@ -0,0 +1,81 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// Index describes an index of an SQL table
+//
+// Indexes are declared with the sql tag options of the fields of a schema:
+//
+//	index             creates an index on the column
+//	index=name        creates the index "name", fields with the same index name create a composite index
+//	unique            creates a unique index on the column
+//	unique=name       creates the unique index "name", fields with the same index name create a composite index
+//	where=condition   makes the index partial (its commas must be within parentheses or quotes, e.g.: where=status IN ('open', 'closed'))
+type Index struct {
+	Name    string
+	Columns []string
+	Unique  bool
+	Where   string
+}
+
+// getIndexes collects the indexes of the fields of a table
+//
+// Indexes without a name are named after the table and their column
+func getIndexes(table string, fields []schemaField) []Index {
+	indexes := []Index{}
+	positions := map[string]int{}
+	for _, field := range fields {
+		if !field.Options.Index {
+			continue
+		}
+		name := field.Options.IndexName
+		if len(name) == 0 {
+			name = fmt.Sprintf("%s_%s_idx", strings.ReplaceAll(table, ".", "_"), field.Column)
+		}
+		position, found := positions[name]
+		if !found {
+			position = len(indexes)
+			positions[name] = position
+			indexes = append(indexes, Index{Name: name, Columns: []string{}})
+		}
+		index := &indexes[position]
+		index.Columns = append(index.Columns, field.Column)
+		index.Unique = index.Unique || field.Options.Unique
+		if len(index.Where) == 0 {
+			index.Where = field.Options.IndexWhere
+		}
+	}
+	return indexes
+}
+
+// createIndex builds the CREATE INDEX statement of an index with the given Dialect
+//
+// If the database does not support partial indexes, partial must be false
+func createIndex(dialect Dialect, table string, index Index, partial bool) (string, error) {
+	if len(index.Columns) == 0 {
+		return "", errors.ArgumentMissing.With("columns").WithStack()
+	}
+	if len(index.Where) > 0 && !partial {
+		return "", errors.Unsupported.With("partial index", index.Name).WithStack()
+	}
+	columns := make([]string, len(index.Columns))
+	for i, column := range index.Columns {
+		columns[i] = dialect.Quote(column)
+	}
+	statement := strings.Builder{}
+	statement.WriteString("CREATE ")
+	if index.Unique {
+		statement.WriteString("UNIQUE ")
+	}
+	statement.WriteString(fmt.Sprintf("INDEX %s ON %s (%s)", dialect.Quote(index.Name), dialect.Quote(table), strings.Join(columns, ", ")))
+	if len(index.Where) > 0 {
+		statement.WriteString(" WHERE ")
+		statement.WriteString(index.Where)
+	}
+	return statement.String(), nil
+}
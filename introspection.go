@@ -0,0 +1,211 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// Introspector is implemented by the Dialects that can describe the tables of a database (see DB.Tables and DB.DescribeTable)
+type Introspector interface {
+	// TablesQuery returns the query that lists the tables of the database, one table name per row
+	TablesQuery() string
+
+	// DescribeQueries returns the queries that describe a table, they all take the returned parameters
+	DescribeQueries(table string) (CatalogQueries, []interface{})
+}
+
+// CatalogQueries are the queries that describe a table
+type CatalogQueries struct {
+	// Columns returns one row per column: name, type, nullable, default (NULL if none)
+	Columns string
+
+	// PrimaryKey returns one row per column of the primary key, in the order of the key: name
+	PrimaryKey string
+
+	// Indexes returns one row per column of each index, not including the primary key, in the order of the index: index name, column name, unique, where condition ('' if none)
+	Indexes string
+
+	// ForeignKeys returns one row per column of each foreign key, in the order of the key: constraint name, column name, foreign table, foreign column, on delete, on update
+	ForeignKeys string
+}
+
+// TableDescription describes a table of a database
+type TableDescription struct {
+	Name        string
+	Columns     []ColumnDescription
+	PrimaryKey  []string
+	Indexes     []Index
+	ForeignKeys []ForeignKeyDescription
+}
+
+// ColumnDescription describes a column of a table
+type ColumnDescription struct {
+	Name     string
+	Type     string
+	Nullable bool
+	Default  string // the SQL expression of the default, empty if the column has no default
+}
+
+// ForeignKeyDescription describes a FOREIGN KEY constraint of a table
+type ForeignKeyDescription struct {
+	Name           string
+	Columns        []string
+	ForeignTable   string
+	ForeignColumns []string
+	OnDelete       string
+	OnUpdate       string
+}
+
+// Column returns the description of the given column
+func (description TableDescription) Column(name string) (ColumnDescription, bool) {
+	for _, column := range description.Columns {
+		if strings.EqualFold(column.Name, name) {
+			return column, true
+		}
+	}
+	return ColumnDescription{}, false
+}
+
+// Tables returns the names of the tables of the database
+//
+// If the Dialect of the DB is not an Introspector, an errors.Unsupported is returned
+func (db *DB) Tables(ctx context.Context) ([]string, error) {
+	introspector, err := db.introspector()
+	if err != nil {
+		return []string{}, err
+	}
+	rows, err := db.db.QueryContext(ctx, introspector.TablesQuery())
+	if err != nil {
+		return []string{}, err
+	}
+	defer rows.Close()
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return []string{}, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, rows.Err()
+}
+
+// DescribeTable describes a table of the database: its columns, primary key, indexes, and foreign keys
+//
+// If the table does not exist, an errors.NotFound is returned.
+// If the Dialect of the DB is not an Introspector, an errors.Unsupported is returned
+func (db *DB) DescribeTable(ctx context.Context, table string) (TableDescription, error) {
+	introspector, err := db.introspector()
+	if err != nil {
+		return TableDescription{}, err
+	}
+	queries, parms := introspector.DescribeQueries(table)
+	description := TableDescription{Name: table}
+
+	err = db.queryRows(ctx, queries.Columns, parms, func(rows *gosql.Rows) error {
+		column := ColumnDescription{}
+		value := gosql.NullString{}
+		if err := rows.Scan(&column.Name, &column.Type, &column.Nullable, &value); err != nil {
+			return err
+		}
+		column.Default = value.String
+		description.Columns = append(description.Columns, column)
+		return nil
+	})
+	if err != nil {
+		return TableDescription{}, err
+	}
+	if len(description.Columns) == 0 {
+		return TableDescription{}, errors.NotFound.With("table", table).WithStack()
+	}
+
+	err = db.queryRows(ctx, queries.PrimaryKey, parms, func(rows *gosql.Rows) error {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return err
+		}
+		description.PrimaryKey = append(description.PrimaryKey, column)
+		return nil
+	})
+	if err != nil {
+		return TableDescription{}, err
+	}
+
+	if description.Indexes, err = db.describeIndexes(ctx, queries.Indexes, parms); err != nil {
+		return TableDescription{}, err
+	}
+
+	err = db.queryRows(ctx, queries.ForeignKeys, parms, func(rows *gosql.Rows) error {
+		var name, column, foreignTable, foreignColumn, onDelete, onUpdate string
+		if err := rows.Scan(&name, &column, &foreignTable, &foreignColumn, &onDelete, &onUpdate); err != nil {
+			return err
+		}
+		last := len(description.ForeignKeys) - 1
+		if last < 0 || description.ForeignKeys[last].Name != name {
+			description.ForeignKeys = append(description.ForeignKeys, ForeignKeyDescription{
+				Name:         name,
+				ForeignTable: foreignTable,
+				OnDelete:     strings.ReplaceAll(strings.ToUpper(onDelete), "_", " "),
+				OnUpdate:     strings.ReplaceAll(strings.ToUpper(onUpdate), "_", " "),
+			})
+			last++
+		}
+		foreignKey := &description.ForeignKeys[last]
+		foreignKey.Columns = append(foreignKey.Columns, column)
+		foreignKey.ForeignColumns = append(foreignKey.ForeignColumns, foreignColumn)
+		return nil
+	})
+	if err != nil {
+		return TableDescription{}, err
+	}
+	return description, nil
+}
+
+// introspector returns the Dialect of the DB as an Introspector
+func (db *DB) introspector() (Introspector, error) {
+	dialect := getDialect(db)
+	if introspector, ok := dialect.(Introspector); ok {
+		return introspector, nil
+	}
+	return nil, errors.Unsupported.With("introspection", dialect.Name()).WithStack()
+}
+
+// describeIndexes runs the query that describes the indexes of a table
+func (db *DB) describeIndexes(ctx context.Context, query string, parms []interface{}) ([]Index, error) {
+	indexes := []Index{}
+	err := db.queryRows(ctx, query, parms, func(rows *gosql.Rows) error {
+		var name, column, where string
+		var unique bool
+		if err := rows.Scan(&name, &column, &unique, &where); err != nil {
+			return err
+		}
+		last := len(indexes) - 1
+		if last < 0 || indexes[last].Name != name {
+			indexes = append(indexes, Index{Name: name, Unique: unique, Where: where})
+			last++
+		}
+		indexes[last].Columns = append(indexes[last].Columns, column)
+		return nil
+	})
+	return indexes, err
+}
+
+// queryRows runs a query and calls scan for each row
+//
+// The query runs directly on the database, outside of any transaction and without preparing it
+func (db *DB) queryRows(ctx context.Context, query string, parms []interface{}, scan func(rows *gosql.Rows) error) error {
+	rows, err := db.db.QueryContext(ctx, query, parms...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
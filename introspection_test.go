@@ -0,0 +1,153 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	"github.com/stretchr/testify/suite"
+)
+
+type IntrospectionSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+// plainDialect is a Dialect that cannot describe tables
+type plainDialect struct {
+	sql.Dialect
+}
+
+func TestIntrospectionSuite(t *testing.T) {
+	suite.Run(t, new(IntrospectionSuite))
+}
+
+func (suite *IntrospectionSuite) TestCanListTables() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.MySQLDialect{}
+	Respond(suite.T().Name(), sql.MySQLDialect{}.TablesQuery(), []string{"table_name VARCHAR"}, []driver.Value{"department"}, []driver.Value{"member"})
+
+	tables, err := db.Tables(context.Background())
+	suite.Require().Nil(err, "Failed to list the tables")
+	suite.Assert().Equal([]string{"department", "member"}, tables)
+	suite.Assert().Equal([]string{
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *IntrospectionSuite) TestCanDescribeTable() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	queries, parms := sql.PostgresDialect{}.DescribeQueries("sales.membership")
+	suite.Assert().Equal([]interface{}{"sales", "membership"}, parms)
+	Respond(suite.T().Name(), queries.Columns, []string{"column_name VARCHAR", "data_type VARCHAR", "nullable BOOL", "column_default VARCHAR"},
+		[]driver.Value{"team", "CHARACTER VARYING(40)", false, nil},
+		[]driver.Value{"member", "INTEGER", false, nil},
+		[]driver.Value{"role", "CHARACTER VARYING(80)", true, "'member'::character varying"},
+	)
+	Respond(suite.T().Name(), queries.PrimaryKey, []string{"column_name VARCHAR"}, []driver.Value{"team"}, []driver.Value{"member"})
+	Respond(suite.T().Name(), queries.Indexes, []string{"relname NAME", "attname NAME", "indisunique BOOL", "indpred TEXT"},
+		[]driver.Value{"membership_role_idx", "role", false, "(role <> 'member'::text)"},
+		[]driver.Value{"membership_unique_idx", "member", true, ""},
+		[]driver.Value{"membership_unique_idx", "role", true, ""},
+	)
+	Respond(suite.T().Name(), queries.ForeignKeys, []string{"constraint_name VARCHAR", "column_name VARCHAR", "table_name VARCHAR", "column_name VARCHAR", "delete_rule VARCHAR", "update_rule VARCHAR"},
+		[]driver.Value{"membership_team_fkey", "team", "team", "id", "CASCADE", "NO ACTION"},
+	)
+
+	description, err := db.DescribeTable(context.Background(), "sales.membership")
+	suite.Require().Nil(err, "Failed to describe the table")
+	suite.Assert().Equal(sql.TableDescription{
+		Name: "sales.membership",
+		Columns: []sql.ColumnDescription{
+			{Name: "team", Type: "CHARACTER VARYING(40)"},
+			{Name: "member", Type: "INTEGER"},
+			{Name: "role", Type: "CHARACTER VARYING(80)", Nullable: true, Default: "'member'::character varying"},
+		},
+		PrimaryKey: []string{"team", "member"},
+		Indexes: []sql.Index{
+			{Name: "membership_role_idx", Columns: []string{"role"}, Where: "(role <> 'member'::text)"},
+			{Name: "membership_unique_idx", Columns: []string{"member", "role"}, Unique: true},
+		},
+		ForeignKeys: []sql.ForeignKeyDescription{
+			{Name: "membership_team_fkey", Columns: []string{"team"}, ForeignTable: "team", ForeignColumns: []string{"id"}, OnDelete: "CASCADE", OnUpdate: "NO ACTION"},
+		},
+	}, description)
+	column, found := description.Column("ROLE")
+	suite.Require().True(found, "The column should be found")
+	suite.Assert().True(column.Nullable)
+	suite.Assert().Equal([]string{queries.Columns, queries.PrimaryKey, queries.Indexes, queries.ForeignKeys}, Recorded(suite.T().Name()))
+}
+
+func (suite *IntrospectionSuite) TestShouldFailDescribingMissingTable() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.SQLServerDialect{}
+
+	_, err = db.DescribeTable(context.Background(), "nowhere")
+	suite.Require().NotNil(err, "Describing a missing table should fail")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+	suite.Assert().Len(Recorded(suite.T().Name()), 1, "Only the columns should be queried")
+}
+
+func (suite *IntrospectionSuite) TestShouldNotDescribeWithUnsupportedDialect() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = plainDialect{sql.PostgresDialect{}}
+
+	_, err = db.Tables(context.Background())
+	suite.Require().NotNil(err, "Listing tables should fail")
+	suite.Assert().Truef(errors.Is(err, errors.Unsupported), "Error should be an Unsupported, was: %s", err)
+	_, err = db.DescribeTable(context.Background(), "anything")
+	suite.Require().NotNil(err, "Describing a table should fail")
+	suite.Assert().Truef(errors.Is(err, errors.Unsupported), "Error should be an Unsupported, was: %s", err)
+	suite.Assert().Empty(Recorded(suite.T().Name()))
+}
+
+// Suite Tools
+
+func (suite *IntrospectionSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *IntrospectionSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *IntrospectionSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *IntrospectionSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
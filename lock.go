@@ -0,0 +1,259 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// DefaultLocksTable is the table where the Locks are recorded
+const DefaultLocksTable = "schema_locks"
+
+// Lock is a lock shared by all the processes that use the same database
+//
+// A Lock is a row of a lock table (DefaultLocksTable unless told otherwise), which is created when needed.
+// The Lock is leased: while it is held, a heartbeat extends its lease.
+// If its owner dies, the lease expires and another process can acquire the Lock.
+//
+// The leases are computed with the clock of the processes, their clocks should be synchronized well within the Lease
+type Lock struct {
+	DB    *DB
+	Table string
+	Name  string
+
+	// Owner identifies the process that holds the Lock, it defaults to hostname:pid:random
+	Owner string
+
+	// Lease is how long the Lock stays held without a heartbeat, the heartbeat runs every third of it
+	Lease time.Duration
+
+	// Timeout is how long Acquire waits for the Lock, 0 waits until the context is done
+	Timeout time.Duration
+
+	// Poll is how often Acquire checks if the Lock was released
+	Poll time.Duration
+
+	Logger *logger.Logger
+
+	mutex sync.Mutex
+	stop  chan struct{}
+	done  chan struct{}
+	lost  error
+}
+
+// NewLock creates a new Lock with the given name for the given DB
+//
+// The Lock has a lease of 30 seconds and Acquire waits for it at most 5 minutes
+func NewLock(db *DB, name string) *Lock {
+	hostname, _ := os.Hostname()
+	return &Lock{
+		DB:      db,
+		Table:   DefaultLocksTable,
+		Name:    name,
+		Owner:   fmt.Sprintf("%s:%d:%x", hostname, os.Getpid(), time.Now().UnixNano()),
+		Lease:   30 * time.Second,
+		Timeout: 5 * time.Minute,
+		Poll:    time.Second,
+		Logger:  db.Logger.Child("lock", "lock", "lock", name),
+	}
+}
+
+// Acquire acquires the Lock, waiting for the other processes to release it
+//
+// If the Lock cannot be acquired within the Timeout, an error that wraps context.DeadlineExceeded is returned
+func (lock *Lock) Acquire(ctx context.Context) error {
+	return lock.acquire(ctx, nil)
+}
+
+// acquire acquires the Lock, the heartbeat calls onLost if the lease of the Lock is lost
+func (lock *Lock) acquire(ctx context.Context, onLost func()) error {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+	if lock.stop != nil {
+		return errors.DuplicateFound.With("lock", lock.Name).WithStack()
+	}
+	if err := lock.createTable(ctx); err != nil {
+		return err
+	}
+	if lock.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, lock.Timeout)
+		defer cancel()
+	}
+	for {
+		acquired, owner, err := lock.tryAcquire(ctx)
+		if err != nil {
+			return err
+		}
+		if acquired {
+			lock.Logger.Infof("Acquired lock %s", lock.Name)
+			lock.stop = make(chan struct{})
+			lock.done = make(chan struct{})
+			lock.lost = nil
+			go lock.heartbeat(lock.stop, lock.done, onLost)
+			return nil
+		}
+		lock.Logger.Debugf("Lock %s is held by %s, waiting", lock.Name, owner)
+		select {
+		case <-ctx.Done():
+			return errors.Wrapf(ctx.Err(), "Failed to acquire lock %s held by %s", lock.Name, owner)
+		case <-time.After(lock.Poll):
+		}
+	}
+}
+
+// Release releases the Lock
+//
+// Releasing a Lock that is not held does nothing.
+// If the lease of the Lock was lost while it was held, the error that made it lost is returned
+func (lock *Lock) Release(ctx context.Context) error {
+	lock.mutex.Lock()
+	defer lock.mutex.Unlock()
+	if lock.stop == nil {
+		return nil
+	}
+	close(lock.stop)
+	<-lock.done
+	lock.stop, lock.done = nil, nil
+	dialect := getDialect(lock.DB)
+	statement := fmt.Sprintf("DELETE FROM %s WHERE name = %s AND owner = %s", dialect.Quote(lock.Table), dialect.Placeholder(1), dialect.Placeholder(2))
+	if _, err := lock.DB.db.ExecContext(ctx, statement, lock.Name, lock.Owner); err != nil && lock.lost == nil {
+		return err
+	}
+	if lock.lost != nil {
+		return lock.lost
+	}
+	lock.Logger.Infof("Released lock %s", lock.Name)
+	return nil
+}
+
+// Do runs the given func while holding the Lock
+//
+// If the lease of the Lock is lost, the context given to the func is cancelled and Do returns the error that made it lost.
+// The Lock is released with its own context that expires after the Lease, so it is released even if ctx is already done
+func (lock *Lock) Do(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	lost := false
+	if err = lock.acquire(ctx, func() { lost = true; cancel() }); err != nil {
+		return err
+	}
+	defer func() {
+		releaseCtx, releaseCancel := context.WithTimeout(context.Background(), lock.Lease)
+		defer releaseCancel()
+		// Release waits for the heartbeat, lost is not written anymore
+		if releaseErr := lock.Release(releaseCtx); releaseErr != nil && (err == nil || lost) {
+			err = releaseErr
+		}
+	}()
+	return fn(ctx)
+}
+
+// tryAcquire tries to acquire the Lock once, if it is held, its owner is returned
+//
+// An expired Lock is removed first. The primary key of the lock table guarantees that only one process inserts the Lock.
+// The statements run directly on the database, outside of any transaction
+func (lock *Lock) tryAcquire(ctx context.Context) (acquired bool, owner string, err error) {
+	dialect := getDialect(lock.DB)
+	table := dialect.Quote(lock.Table)
+	now := time.Now().UTC()
+
+	statement := fmt.Sprintf("DELETE FROM %s WHERE name = %s AND expires_at < %s", table, dialect.Placeholder(1), dialect.Placeholder(2))
+	if _, err := lock.DB.db.ExecContext(ctx, statement, lock.Name, now); err != nil {
+		return false, "", err
+	}
+	statement = fmt.Sprintf("INSERT INTO %s (name, owner, expires_at) VALUES (%s, %s, %s)", table, dialect.Placeholder(1), dialect.Placeholder(2), dialect.Placeholder(3))
+	_, insertErr := lock.DB.db.ExecContext(ctx, statement, lock.Name, lock.Owner, now.Add(lock.Lease))
+	if insertErr == nil {
+		return true, lock.Owner, nil
+	}
+	statement = fmt.Sprintf("SELECT owner FROM %s WHERE name = %s", table, dialect.Placeholder(1))
+	if err := lock.DB.db.QueryRowContext(ctx, statement, lock.Name).Scan(&owner); err != nil {
+		return false, "", insertErr // the Lock is not held, the insert failed for another reason
+	}
+	return false, owner, nil
+}
+
+// heartbeat extends the lease of the Lock until stop is closed or the lease is lost
+//
+// The lease is lost when it cannot be extended, the error is kept for Release and onLost is called.
+// Each extension must complete within the Lease and is cancelled when stop is closed, so Release never waits for a hanging database
+func (lock *Lock) heartbeat(stop, done chan struct{}, onLost func()) {
+	defer close(done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	dialect := getDialect(lock.DB)
+	statement := fmt.Sprintf("UPDATE %s SET expires_at = %s WHERE name = %s AND owner = %s", dialect.Quote(lock.Table), dialect.Placeholder(1), dialect.Placeholder(2), dialect.Placeholder(3))
+	ticker := time.NewTicker(lock.Lease / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			err := lock.extend(ctx, statement)
+			if ctx.Err() != nil {
+				return // stopped while extending, the Lock is being released
+			}
+			if err != nil {
+				lock.Logger.Errorf("Lock %s was lost, its lease could not be extended: %v", lock.Name, err)
+				lock.lost = errors.Wrapf(err, "Lock %s was lost", lock.Name)
+				if onLost != nil {
+					onLost()
+				}
+				return
+			}
+		}
+	}
+}
+
+// extend extends the lease of the Lock once, within the Lease
+func (lock *Lock) extend(ctx context.Context, statement string) error {
+	ctx, cancel := context.WithTimeout(ctx, lock.Lease)
+	defer cancel()
+	result, err := lock.DB.db.ExecContext(ctx, statement, time.Now().UTC().Add(lock.Lease), lock.Name, lock.Owner)
+	if err != nil {
+		return err
+	}
+	if count, err := result.RowsAffected(); err == nil && count == 0 {
+		return errors.NotFound.With("lock", lock.Name).WithStack()
+	}
+	return nil
+}
+
+// createTable creates the lock table if it does not exist
+//
+// Several processes can try to create the table at the same time, if the creation fails the table is checked again
+func (lock *Lock) createTable(ctx context.Context) error {
+	dialect := getDialect(lock.DB)
+	table := dialect.Quote(lock.Table)
+	exists := func() bool {
+		var count int64
+		err := lock.DB.db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&count)
+		return err == nil || err == gosql.ErrNoRows
+	}
+	if exists() {
+		return nil
+	}
+	timeType, _ := dialect.SQLType("expires_at", reflect.TypeOf(time.Time{}))
+	statement := fmt.Sprintf("CREATE TABLE %s (name VARCHAR(255) PRIMARY KEY, owner VARCHAR(255) NOT NULL, expires_at %s NOT NULL)", table, timeType)
+	lock.Logger.Infof("Creating the locks table %s", lock.Table)
+	if _, err := lock.DB.db.ExecContext(ctx, statement); err != nil && !exists() {
+		return errors.CreationFailed.With("table", lock.Table).Wrap(err)
+	}
+	return nil
+}
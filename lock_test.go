@@ -0,0 +1,240 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	_ "github.com/proullon/ramsql/driver"
+	"github.com/stretchr/testify/suite"
+)
+
+type LockSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+func TestLockSuite(t *testing.T) {
+	suite.Run(t, new(LockSuite))
+}
+
+func (suite *LockSuite) TestCanAcquireAndReleaseLock() {
+	ctx := context.Background()
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	first := sql.NewLock(db, "jobs")
+	second := sql.NewLock(db, "jobs")
+	second.Timeout = 50 * time.Millisecond
+	second.Poll = 10 * time.Millisecond
+	suite.Require().NotEqual(first.Owner, second.Owner, "Each Lock should have its own owner")
+
+	suite.Require().Nil(first.Acquire(ctx), "Failed to acquire the lock")
+	err = first.Acquire(ctx)
+	suite.Require().NotNil(err, "A lock cannot be acquired twice")
+	suite.Assert().Truef(errors.Is(err, errors.DuplicateFound), "Error should be a DuplicateFound, was: %s", err)
+
+	err = second.Acquire(ctx)
+	suite.Require().NotNil(err, "The lock should be held by the first owner")
+	suite.Assert().Truef(errors.Is(err, context.DeadlineExceeded), "Error should be a DeadlineExceeded, was: %s", err)
+
+	other := sql.NewLock(db, "reports")
+	suite.Require().Nil(other.Acquire(ctx), "Locks with other names should not be held")
+	suite.Require().Nil(other.Release(ctx), "Failed to release the other lock")
+
+	suite.Require().Nil(first.Release(ctx), "Failed to release the lock")
+	suite.Require().Nil(first.Release(ctx), "Releasing a lock that is not held should do nothing")
+	suite.Require().Nil(second.Acquire(ctx), "Failed to acquire the released lock")
+	suite.Require().Nil(second.Release(ctx), "Failed to release the lock")
+}
+
+func (suite *LockSuite) TestCanAcquireExpiredLock() {
+	ctx := context.Background()
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	lock := sql.NewLock(db, "jobs")
+	lock.Timeout = time.Second
+	suite.Require().Nil(lock.Do(ctx, func(ctx context.Context) error { return nil }), "Failed to create the locks table")
+	_, err = db.ExecContext(ctx, "INSERT INTO schema_locks (name, owner, expires_at) VALUES ($1, $2, $3)", "jobs", "crashed", time.Now().UTC().Add(-time.Minute))
+	suite.Require().Nil(err, "Failed to insert an expired lock")
+
+	suite.Require().Nil(lock.Acquire(ctx), "The expired lock should be acquired")
+	suite.Require().Nil(lock.Release(ctx), "Failed to release the lock")
+}
+
+func (suite *LockSuite) TestHeartbeatShouldKeepLock() {
+	ctx := context.Background()
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	first := sql.NewLock(db, "jobs")
+	first.Lease = 300 * time.Millisecond
+	second := sql.NewLock(db, "jobs")
+	second.Timeout = 10 * time.Millisecond
+	second.Poll = 5 * time.Millisecond
+
+	suite.Require().Nil(first.Acquire(ctx), "Failed to acquire the lock")
+	defer first.Release(ctx)
+	acquired := lockExpiresAt(suite, db, "jobs")
+	suite.Require().Eventually(func() bool {
+		return lockExpiresAt(suite, db, "jobs").After(acquired)
+	}, 10*first.Lease, first.Lease/10, "The heartbeat should have extended the lease")
+	err = second.Acquire(ctx)
+	suite.Require().NotNil(err, "The heartbeat should have extended the lease")
+	suite.Assert().Truef(errors.Is(err, context.DeadlineExceeded), "Error should be a DeadlineExceeded, was: %s", err)
+}
+
+func (suite *LockSuite) TestShouldCancelDoWhenLockIsLost() {
+	ctx := context.Background()
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	lock := sql.NewLock(db, "jobs")
+	lock.Lease = 30 * time.Millisecond
+	err = lock.Do(ctx, func(ctx context.Context) error {
+		_, err := db.ExecContext(context.Background(), "DELETE FROM schema_locks WHERE name = $1", "jobs")
+		suite.Require().Nil(err, "Failed to steal the lock")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	suite.Require().NotNil(err, "Do should fail when the lock is lost")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+	suite.Assert().Contains(err.Error(), "Lock jobs was lost")
+
+	suite.Require().Nil(lock.Do(ctx, func(ctx context.Context) error { return nil }), "The lock should be acquired again")
+}
+
+func (suite *LockSuite) TestShouldReleaseLockWhenContextIsCancelled() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	lock := sql.NewLock(db, "jobs")
+	err = lock.Do(ctx, func(ctx context.Context) error {
+		cancel()
+		return nil
+	})
+	suite.Require().Nil(err, "Failed to run with the lock")
+
+	other := sql.NewLock(db, "jobs")
+	other.Timeout = 10 * time.Millisecond
+	suite.Require().Nil(other.Acquire(context.Background()), "The lock should have been released")
+	suite.Require().Nil(other.Release(context.Background()), "Failed to release the lock")
+}
+
+func (suite *LockSuite) TestMigrationsShouldWaitForLock() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	appliedMigrations(name)
+
+	migrator, err := sql.NewMigrator(db, personMigrations()...)
+	suite.Require().Nil(err, "Failed to create the migrator")
+	migrator.Lock.Timeout = 50 * time.Millisecond
+	migrator.Lock.Poll = 10 * time.Millisecond
+
+	// Another replica holds the lock of the migrations
+	Fail(name, "INSERT INTO schema_locks (name, owner, expires_at) VALUES ($1, $2, $3)", fmt.Errorf("duplicate key value violates unique constraint"))
+	Respond(name, "SELECT owner FROM schema_locks WHERE name = $1", []string{"owner VARCHAR"}, []driver.Value{"replica"})
+	err = migrator.Migrate(ctx)
+	suite.Require().NotNil(err, "The migrations should wait for the other replica")
+	suite.Assert().Truef(errors.Is(err, context.DeadlineExceeded), "Error should be a DeadlineExceeded, was: %s", err)
+	suite.Assert().Equal(sql.DefaultMigrationsTable, migrator.Lock.Name, "The lock should be named after the migrations table")
+	suite.Assert().Empty(migrationStatements(name), "The migrations should not run without the lock")
+
+	Fail(name, "INSERT INTO schema_locks (name, owner, expires_at) VALUES ($1, $2, $3)", nil)
+	suite.Require().Nil(migrator.Migrate(ctx), "The migrations should run once the lock is released")
+	suite.Assert().Contains(migrationStatements(name), "CREATE TABLE plant (id VARCHAR(80) PRIMARY KEY)", "The migrations should be applied")
+}
+
+func (suite *LockSuite) TestCanLockWithOtherDialects() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.SQLServerDialect{}
+
+	lock := sql.NewLock(db, "jobs")
+	lock.Table = "ops.locks"
+	suite.Require().Nil(lock.Do(context.Background(), func(ctx context.Context) error { return nil }), "Failed to lock")
+	suite.Assert().Equal([]string{
+		"SELECT COUNT(*) FROM ops.locks",
+		"DELETE FROM ops.locks WHERE name = @p1 AND expires_at < @p2",
+		"INSERT INTO ops.locks (name, owner, expires_at) VALUES (@p1, @p2, @p3)",
+		"DELETE FROM ops.locks WHERE name = @p1 AND owner = @p2",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *LockSuite) TestShouldReleaseLockWhenHeartbeatHangs() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	Delay(suite.T().Name(), "UPDATE schema_locks SET expires_at = $1 WHERE name = $2 AND owner = $3", time.Hour)
+
+	lock := sql.NewLock(db, "jobs")
+	lock.Lease = 30 * time.Millisecond
+	start := time.Now()
+	err = lock.Do(context.Background(), func(ctx context.Context) error {
+		time.Sleep(2 * lock.Lease / 3) // the heartbeat hangs on the database
+		return nil
+	})
+	suite.Require().Nil(err, "Failed to run with the lock")
+	suite.Assert().Less(time.Since(start), time.Second, "Do should not wait for the hanging heartbeat")
+	suite.Assert().Contains(Recorded(suite.T().Name()), "DELETE FROM schema_locks WHERE name = $1 AND owner = $2", "The lock should be released")
+}
+
+// Suite Tools
+
+func lockExpiresAt(suite *LockSuite, db *sql.DB, name string) (expiresAt time.Time) {
+	err := db.QueryRowContext(context.Background(), "SELECT expires_at FROM schema_locks WHERE name = $1", name).Scan(&expiresAt)
+	suite.Require().Nil(err, "Failed to read the lease of the lock")
+	return expiresAt
+}
+
+func (suite *LockSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *LockSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *LockSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *LockSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
@@ -0,0 +1,300 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gildas/go-errors"
+)
+
+// ChangePhase is a phase of an expand/contract schema change
+//
+// A change is expanded, then all the instances of the application are deployed so they write both the old and the new column
+// (the dual-write period), then the change is contracted.
+type ChangePhase int
+
+const (
+	// PhaseExpand adds the new column and copies the existing rows into it, the old column is kept
+	PhaseExpand ChangePhase = iota
+	// PhaseContract copies the rows that were not copied yet and removes the old column
+	PhaseContract
+)
+
+// ColumnChange describes a schema change of a column that is applied without downtime
+//
+// The rows are copied by batches ordered by Key, each batch runs in its own statement outside of any transaction
+// so the table is never locked for long. The copy only fills the rows where the new column is NULL,
+// so an interrupted change can be run again, it resumes where it stopped.
+//
+// Some databases (e.g.: SQLite) cannot remove a column that is indexed, its indexes must be removed before contracting.
+type ColumnChange struct {
+	// Table is the table to change
+	Table string
+
+	// Column is the column to change or to add
+	Column string
+
+	// NewColumn is the new name of the column (RenameColumn) or its shadow column (ChangeColumnType, defaults to Column + "_new")
+	NewColumn string
+
+	// Type is the SQL type of the new column, it should be nullable as it is added to a table that contains rows
+	Type string
+
+	// Using is the SQL expression that computes the new column from the row (e.g.: "lower(email)"), it defaults to Column
+	Using string
+
+	// Key is the column that orders the batches, it defaults to "id"
+	Key string
+
+	// BatchSize is the number of rows copied per statement, it defaults to 1000
+	BatchSize int
+
+	// Pause is the time to wait between batches to lower the load of the database
+	Pause time.Duration
+}
+
+// AddColumnWithBackfill adds a column to a table and fills its existing rows by batches
+//
+// Using is mandatory, it gives the value of the column for the existing rows.
+// If the column exists already, only the rows where it is NULL are filled.
+func (db *DB) AddColumnWithBackfill(ctx context.Context, change ColumnChange) error {
+	if err := change.validate(); err != nil {
+		return err
+	}
+	if len(change.Using) == 0 {
+		return errors.ArgumentMissing.With("using").WithStack()
+	}
+	columns, err := db.tableColumns(ctx, change.Table)
+	if err != nil {
+		return err
+	}
+	if _, found := columns[strings.ToLower(change.Column)]; !found {
+		if err := db.addColumn(ctx, change, change.Column); err != nil {
+			return err
+		}
+	}
+	return db.backfill(ctx, change, change.Column, change.Using)
+}
+
+// RenameColumn renames a column of a table without downtime
+//
+// PhaseExpand adds the new column (of the given Type) and copies the old column into it.
+// During the dual-write period, the application writes both columns.
+// PhaseContract copies the rows written only in the old column and removes the old column.
+func (db *DB) RenameColumn(ctx context.Context, change ColumnChange, phase ChangePhase) error {
+	if err := change.validate(); err != nil {
+		return err
+	}
+	if len(change.NewColumn) == 0 {
+		return errors.ArgumentMissing.With("newcolumn").WithStack()
+	}
+	return db.expandOrContract(ctx, change, change.NewColumn, phase, nil)
+}
+
+// ChangeColumnType changes the type of a column without downtime, through a shadow column
+//
+// PhaseExpand adds the shadow column (of the given Type) and copies the column into it, converted with Using.
+// During the dual-write period, the application writes both columns.
+// PhaseContract copies the rows written only in the column, removes the column, and renames the shadow column as the column.
+// If the Dialect supports transactional DDL, the column is removed and replaced in one transaction.
+//
+// Once the column has the given Type (e.g.: after PhaseContract), PhaseExpand does nothing.
+func (db *DB) ChangeColumnType(ctx context.Context, change ColumnChange, phase ChangePhase) error {
+	if err := change.validate(); err != nil {
+		return err
+	}
+	shadow := change.NewColumn
+	if len(shadow) == 0 {
+		shadow = change.Column + "_new"
+	}
+	return db.expandOrContract(ctx, change, shadow, phase, func(ctx context.Context, runner Runner) error {
+		_, err := runner.ExecContext(ctx, getDialect(db).RenameColumn(change.Table, shadow, change.Column))
+		return err
+	})
+}
+
+// expandOrContract runs a phase of a change that copies a column into a new column
+//
+// When contracting, replace runs after the column is removed, in the same transaction if the Dialect supports transactional DDL.
+// The phases check the columns of the table first, so they can run again after an interruption
+func (db *DB) expandOrContract(ctx context.Context, change ColumnChange, newColumn string, phase ChangePhase, replace MigrationFunc) error {
+	columns, err := db.tableColumns(ctx, change.Table)
+	if err != nil {
+		return err
+	}
+	columnType, hasColumn := columns[strings.ToLower(change.Column)]
+	_, hasNewColumn := columns[strings.ToLower(newColumn)]
+	using := change.Using
+	if len(using) == 0 {
+		using = getDialect(db).Quote(change.Column)
+	}
+	switch phase {
+	case PhaseExpand:
+		if !hasColumn {
+			if hasNewColumn {
+				return nil // the change was contracted already
+			}
+			return errors.NotFound.With("column", change.Column).WithStack()
+		}
+		if !hasNewColumn && replace != nil && len(columnType) > 0 && normalizeSQLType(columnType) == normalizeSQLType(change.Type) {
+			return nil // the shadow column replaced the column already
+		}
+		if !hasNewColumn {
+			if err := db.addColumn(ctx, change, newColumn); err != nil {
+				return err
+			}
+		}
+		return db.backfill(ctx, change, newColumn, using)
+	case PhaseContract:
+		if !hasNewColumn {
+			if hasColumn && replace != nil {
+				return nil // the shadow column replaced the column already
+			}
+			return errors.NotFound.With("column", newColumn).WithStack()
+		}
+		if !hasColumn && replace == nil {
+			return nil // the column was removed already
+		}
+		if hasColumn {
+			if err := db.backfill(ctx, change, newColumn, using); err != nil {
+				return err
+			}
+		}
+		contract := func(ctx context.Context, runner Runner) error {
+			if hasColumn {
+				db.Logger.Child(nil, "contract").Infof("Removing column %s from %s", change.Column, change.Table)
+				if _, err := runner.ExecContext(ctx, getDialect(db).DropColumn(change.Table, change.Column)); err != nil {
+					return err
+				}
+			}
+			if replace != nil {
+				return replace(ctx, runner)
+			}
+			return nil
+		}
+		if getDialect(db).TransactionalDDL() {
+			return db.InTransaction(ctx, func(tx *Tx) error { return contract(tx.Context(), tx) })
+		}
+		return contract(ctx, db)
+	default:
+		return errors.ArgumentInvalid.With("phase", phase).WithStack()
+	}
+}
+
+// validate checks the mandatory fields of a ColumnChange
+func (change ColumnChange) validate() error {
+	if len(change.Table) == 0 {
+		return errors.ArgumentMissing.With("table").WithStack()
+	}
+	if len(change.Column) == 0 {
+		return errors.ArgumentMissing.With("column").WithStack()
+	}
+	return nil
+}
+
+// tableColumns returns the columns of a table, in lowercase, and their database type (empty if the driver does not tell)
+func (db *DB) tableColumns(ctx context.Context, table string) (map[string]string, error) {
+	columns, exists, err := db.session(ctx).existingColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.NotFound.With("table", table).WithStack()
+	}
+	return columns, nil
+}
+
+// addColumn adds a column of the type of the change to its table
+func (db *DB) addColumn(ctx context.Context, change ColumnChange, column string) error {
+	if len(change.Type) == 0 {
+		return errors.ArgumentMissing.With("type").WithStack()
+	}
+	dialect := getDialect(db)
+	statement := dialect.AddColumn(change.Table, dialect.Quote(column)+" "+change.Type)
+	log := db.Logger.Child(nil, "expand").Record("table", change.Table)
+	log.Infof("Adding column %s", column)
+	log.Tracef("Statement: %s", statement)
+	if _, err := db.db.ExecContext(ctx, statement); err != nil {
+		return errors.CreationFailed.With("column", column).Wrap(err)
+	}
+	return nil
+}
+
+// backfill sets a column to the given expression in the rows where it is NULL, by batches ordered by the key of the change
+//
+// The batches follow the key, so rows where the expression is NULL are visited only once.
+// Each batch updates a range of keys, so its statement has at most 2 parameters whatever the size of the batch
+func (db *DB) backfill(ctx context.Context, change ColumnChange, column, expression string) error {
+	dialect := getDialect(db)
+	key := change.Key
+	if len(key) == 0 {
+		key = "id"
+	}
+	batchSize := change.BatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	table, target := dialect.Quote(change.Table), dialect.Quote(column)
+	log := db.Logger.Child(nil, "backfill").Record("table", change.Table).Record("column", column)
+	var last interface{}
+	var total int64
+	for {
+		query := fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NULL", dialect.Quote(key), table, target)
+		parms := []interface{}{}
+		if last != nil {
+			query += fmt.Sprintf(" AND %s > %s", dialect.Quote(key), dialect.Placeholder(1))
+			parms = append(parms, last)
+		}
+		var page string
+		page, parms = dialect.Paginate([]Sort{{Column: key}}, batchSize, 0, parms)
+		query += " " + page
+		keys := []interface{}{}
+		err := db.queryRows(ctx, query, parms, func(rows *gosql.Rows) error {
+			var value interface{}
+			if err := rows.Scan(&value); err != nil {
+				return err
+			}
+			if bytes, ok := value.([]byte); ok {
+				value = string(bytes)
+			}
+			keys = append(keys, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			break
+		}
+		statement := fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s IS NULL AND %s <= %s", table, target, expression, target, dialect.Quote(key), dialect.Placeholder(1))
+		parms = []interface{}{keys[len(keys)-1]}
+		if last != nil {
+			statement += fmt.Sprintf(" AND %s > %s", dialect.Quote(key), dialect.Placeholder(2))
+			parms = append(parms, last)
+		}
+		result, err := db.db.ExecContext(ctx, statement, parms...)
+		if err != nil {
+			return err
+		}
+		if count, err := result.RowsAffected(); err == nil {
+			total += count
+		}
+		log.Debugf("Backfilled %d rows", total)
+		if len(keys) < batchSize {
+			break
+		}
+		last = keys[len(keys)-1]
+		if change.Pause > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(change.Pause):
+			}
+		}
+	}
+	log.Infof("Backfilled %d rows of %s.%s", total, change.Table, column)
+	return nil
+}
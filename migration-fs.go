@@ -0,0 +1,79 @@
+package sql
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// LoadMigrations loads the Migrations written as SQL files in a directory of a file system
+//
+// The files are named <version>_<name>.up.sql and <version>_<name>.down.sql (e.g.: 0001_create_persons.up.sql),
+// the down file is optional.
+//
+// Each file is executed as one statement, if a file contains several statements the database driver must support it
+func LoadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return []Migration{}, err
+	}
+	migrations := map[int64]*Migration{}
+	for _, entry := range entries {
+		filename := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(filename, ".sql") {
+			continue
+		}
+		up := strings.HasSuffix(filename, ".up.sql")
+		if !up && !strings.HasSuffix(filename, ".down.sql") {
+			return []Migration{}, errors.ArgumentInvalid.With("migration", filename).WithStack()
+		}
+		base := strings.TrimSuffix(strings.TrimSuffix(filename, ".down.sql"), ".up.sql")
+		parts := strings.SplitN(base, "_", 2)
+		version, err := strconv.ParseInt(parts[0], 10, 64)
+		if err != nil || version <= 0 {
+			return []Migration{}, errors.ArgumentInvalid.With("migration", filename).WithStack()
+		}
+		name := ""
+		if len(parts) > 1 {
+			name = parts[1]
+		}
+		payload, err := fs.ReadFile(fsys, path.Join(dir, filename))
+		if err != nil {
+			return []Migration{}, err
+		}
+		migration, found := migrations[version]
+		if !found {
+			migration = &Migration{Version: version, Name: name}
+			migrations[version] = migration
+		} else if migration.Name != name {
+			return []Migration{}, errors.ArgumentInvalid.With("migration", filename).WithStack()
+		}
+		if up {
+			migration.Up = execStatement(string(payload))
+		} else {
+			migration.Down = execStatement(string(payload))
+		}
+	}
+	results := make([]Migration, 0, len(migrations))
+	for _, migration := range migrations {
+		if migration.Up == nil {
+			return []Migration{}, errors.ArgumentMissing.With("up").WithStack()
+		}
+		results = append(results, *migration)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Version < results[j].Version })
+	return results, nil
+}
+
+// execStatement returns a MigrationFunc that executes the given statement
+func execStatement(statement string) MigrationFunc {
+	return func(ctx context.Context, runner Runner) error {
+		_, err := runner.ExecContext(ctx, statement)
+		return err
+	}
+}
@@ -0,0 +1,338 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// Runner runs statements and structured operations, it is implemented by *DB and *Tx
+type Runner interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (gosql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*gosql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *gosql.Row
+	CreateTableContext(ctx context.Context, schemas ...interface{}) error
+	DeleteTableContext(ctx context.Context, schemas ...interface{}) error
+	InsertContext(ctx context.Context, blob interface{}) error
+	InsertManyContext(ctx context.Context, blobs interface{}, options InsertManyOptions) error
+	FindAllContext(ctx context.Context, schema interface{}, queries Queries) ([]interface{}, error)
+	UpdateAllContext(ctx context.Context, schema interface{}, queries Queries) error
+	DeleteAllContext(ctx context.Context, schema interface{}, queries Queries) error
+	UpdateAllAffectedContext(ctx context.Context, schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error)
+	DeleteAllAffectedContext(ctx context.Context, schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error)
+	UpdateContext(ctx context.Context, blob interface{}) error
+	DeleteContext(ctx context.Context, blob interface{}) error
+	UpsertContext(ctx context.Context, blob interface{}, options UpsertOptions) error
+}
+
+// MigrationFunc is a step of a Migration
+//
+// If the Dialect supports transactional DDL, runner is the transaction of the Migration and it is also stored in ctx
+type MigrationFunc func(ctx context.Context, runner Runner) error
+
+// Migration is a numbered step in the life of the schema of a database
+type Migration struct {
+	// Version orders the Migrations, it must be unique and greater than 0
+	Version int64
+
+	// Name describes the Migration
+	Name string
+
+	// Up applies the Migration
+	Up MigrationFunc
+
+	// Down reverts the Migration, if it is nil the Migration cannot be rolled back
+	Down MigrationFunc
+}
+
+// MigrationStatus tells if a Migration was applied
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// DefaultMigrationsTable is the table where a Migrator records the applied Migrations
+const DefaultMigrationsTable = "schema_migrations"
+
+// Migrator applies and reverts the Migrations of a DB
+//
+// The applied Migrations are recorded in a bookkeeping table (DefaultMigrationsTable unless told otherwise),
+// which is created when needed.
+//
+// If the Dialect of the DB supports transactional DDL, each Migration runs in its own transaction
+// with the update of the bookkeeping table.
+//
+// Migrations are applied and reverted while holding Lock, so only one process migrates the database at a time,
+// the other processes wait for it. If Lock is nil, no lock is taken. If Lock has no Name, it is named after Table,
+// so Migrators of different tables do not wait for each other.
+type Migrator struct {
+	DB         *DB
+	Table      string
+	Lock       *Lock
+	Logger     *logger.Logger
+	migrations []Migration
+}
+
+// NewMigrator creates a new Migrator for the given DB and Migrations
+func NewMigrator(db *DB, migrations ...Migration) (*Migrator, error) {
+	migrator := &Migrator{
+		DB:         db,
+		Table:      DefaultMigrationsTable,
+		Lock:       NewLock(db, ""),
+		Logger:     db.Logger.Child("migrator", "migrator"),
+		migrations: []Migration{},
+	}
+	if err := migrator.Add(migrations...); err != nil {
+		return nil, err
+	}
+	return migrator, nil
+}
+
+// Add adds Migrations to the Migrator
+//
+// The Version of a Migration must be greater than 0 and unique, the Up step is mandatory
+func (migrator *Migrator) Add(migrations ...Migration) error {
+	for _, migration := range migrations {
+		if migration.Version <= 0 {
+			return errors.ArgumentInvalid.With("version", migration.Version).WithStack()
+		}
+		if migration.Up == nil {
+			return errors.ArgumentMissing.With("up").WithStack()
+		}
+		if _, found := migrator.find(migration.Version); found {
+			return errors.DuplicateFound.With("migration", fmt.Sprintf("%d", migration.Version)).WithStack()
+		}
+		migrator.migrations = append(migrator.migrations, migration)
+	}
+	sort.Slice(migrator.migrations, func(i, j int) bool {
+		return migrator.migrations[i].Version < migrator.migrations[j].Version
+	})
+	return nil
+}
+
+// Migrations returns the Migrations of the Migrator sorted by Version
+func (migrator *Migrator) Migrations() []Migration {
+	return append([]Migration{}, migrator.migrations...)
+}
+
+// Migrate applies all the Migrations that were not applied yet
+func (migrator *Migrator) Migrate(ctx context.Context) error {
+	if len(migrator.migrations) == 0 {
+		return nil
+	}
+	return migrator.MigrateTo(ctx, migrator.migrations[len(migrator.migrations)-1].Version)
+}
+
+// MigrateTo applies or reverts Migrations so the schema is at the given version
+//
+// The Migrations up to the version that were not applied yet are applied in order,
+// the applied Migrations after the version are reverted in reverse order.
+// Version 0 reverts all the Migrations
+func (migrator *Migrator) MigrateTo(ctx context.Context, version int64) error {
+	return migrator.locked(ctx, func(ctx context.Context) error {
+		return migrator.migrateTo(ctx, version)
+	})
+}
+
+// Rollback reverts the last n applied Migrations in reverse order
+func (migrator *Migrator) Rollback(ctx context.Context, n int) error {
+	return migrator.locked(ctx, func(ctx context.Context) error {
+		return migrator.rollback(ctx, n)
+	})
+}
+
+// migrateTo applies or reverts Migrations so the schema is at the given version
+func (migrator *Migrator) migrateTo(ctx context.Context, version int64) error {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(applied) - 1; i >= 0; i-- {
+		if applied[i].Version <= version {
+			break
+		}
+		if err := migrator.down(ctx, applied[i].Version); err != nil {
+			return err
+		}
+	}
+	done := map[int64]bool{}
+	for _, status := range applied {
+		done[status.Version] = true
+	}
+	for _, migration := range migrator.migrations {
+		if migration.Version > version {
+			break
+		}
+		if done[migration.Version] {
+			continue
+		}
+		if err := migrator.run(ctx, migration, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rollback reverts the last n applied Migrations in reverse order
+func (migrator *Migrator) rollback(ctx context.Context, n int) error {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return err
+	}
+	for i := len(applied) - 1; i >= 0 && i >= len(applied)-n; i-- {
+		if err := migrator.down(ctx, applied[i].Version); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Status tells which Migrations were applied
+//
+// The statuses are sorted by Version, they also contain the applied Migrations that the Migrator does not know about
+func (migrator *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := migrator.applied(ctx)
+	if err != nil {
+		return []MigrationStatus{}, err
+	}
+	statuses := make([]MigrationStatus, 0, len(migrator.migrations))
+	for _, migration := range migrator.migrations {
+		statuses = append(statuses, MigrationStatus{Version: migration.Version, Name: migration.Name})
+	}
+	for _, status := range applied {
+		found := false
+		for i := range statuses {
+			if statuses[i].Version == status.Version {
+				statuses[i].Applied = true
+				statuses[i].AppliedAt = status.AppliedAt
+				found = true
+				break
+			}
+		}
+		if !found {
+			statuses = append(statuses, status)
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// locked runs the given func while holding the Lock of the Migrator, if any
+func (migrator *Migrator) locked(ctx context.Context, fn func(ctx context.Context) error) error {
+	if migrator.Lock == nil {
+		return fn(ctx)
+	}
+	if len(migrator.Lock.Name) == 0 {
+		migrator.Lock.Name = migrator.Table
+		migrator.Lock.Logger = migrator.Lock.Logger.Record("lock", migrator.Table)
+	}
+	return migrator.Lock.Do(ctx, fn)
+}
+
+// find finds the Migration with the given version
+func (migrator *Migrator) find(version int64) (Migration, bool) {
+	for _, migration := range migrator.migrations {
+		if migration.Version == version {
+			return migration, true
+		}
+	}
+	return Migration{}, false
+}
+
+// down reverts the applied Migration with the given version
+func (migrator *Migrator) down(ctx context.Context, version int64) error {
+	migration, found := migrator.find(version)
+	if !found {
+		return errors.NotFound.With("migration", fmt.Sprintf("%d", version)).WithStack()
+	}
+	return migrator.run(ctx, migration, false)
+}
+
+// run applies or reverts a Migration and records it in the bookkeeping table
+func (migrator *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	log := migrator.Logger.Record("version", migration.Version).Record("name", migration.Name)
+	step := migration.Up
+	if !up {
+		if migration.Down == nil {
+			return errors.Unsupported.With("rollback", fmt.Sprintf("%d %s", migration.Version, migration.Name)).WithStack()
+		}
+		step = migration.Down
+	}
+	log.Infof("Running migration %d %s (up: %t)", migration.Version, migration.Name, up)
+	if getDialect(migrator.DB).TransactionalDDL() {
+		return migrator.DB.InTransaction(ctx, func(tx *Tx) error {
+			if err := step(tx.Context(), tx); err != nil {
+				log.Errorf("Failed to run migration %d: %v", migration.Version, err)
+				return err
+			}
+			return migrator.record(ctx, tx, migration, up)
+		})
+	}
+	if err := step(ctx, migrator.DB); err != nil {
+		log.Errorf("Failed to run migration %d: %v", migration.Version, err)
+		return err
+	}
+	return migrator.record(ctx, migrator.DB, migration, up)
+}
+
+// record records that a Migration was applied or reverted in the bookkeeping table
+func (migrator *Migrator) record(ctx context.Context, runner Runner, migration Migration, up bool) error {
+	dialect := getDialect(migrator.DB)
+	var err error
+	if up {
+		statement := fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s)", dialect.Quote(migrator.Table), dialect.Placeholder(1), dialect.Placeholder(2), dialect.Placeholder(3))
+		_, err = runner.ExecContext(ctx, statement, migration.Version, migration.Name, time.Now().UTC())
+	} else {
+		statement := fmt.Sprintf("DELETE FROM %s WHERE version = %s", dialect.Quote(migrator.Table), dialect.Placeholder(1))
+		_, err = runner.ExecContext(ctx, statement, migration.Version)
+	}
+	return err
+}
+
+// applied returns the status of the applied Migrations sorted by Version
+func (migrator *Migrator) applied(ctx context.Context) ([]MigrationStatus, error) {
+	if err := migrator.createTable(ctx); err != nil {
+		return []MigrationStatus{}, err
+	}
+	statement := fmt.Sprintf("SELECT version, name, applied_at FROM %s ORDER BY version", getDialect(migrator.DB).Quote(migrator.Table))
+	rows, err := migrator.DB.QueryContext(ctx, statement)
+	if err != nil {
+		return []MigrationStatus{}, err
+	}
+	defer rows.Close()
+	statuses := []MigrationStatus{}
+	for rows.Next() {
+		status := MigrationStatus{Applied: true}
+		if err := rows.Scan(&status.Version, &status.Name, &status.AppliedAt); err != nil {
+			return []MigrationStatus{}, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, rows.Err()
+}
+
+// createTable creates the bookkeeping table if the catalog of the database tells it does not exist
+func (migrator *Migrator) createTable(ctx context.Context) error {
+	missing, err := migrator.DB.session(ctx).missingTable(ctx, migrator.Table)
+	if err != nil || !missing {
+		return err
+	}
+	dialect := getDialect(migrator.DB)
+	table := dialect.Quote(migrator.Table)
+	versionType, _ := dialect.SQLType("version", reflect.TypeOf(int64(0)))
+	timeType, _ := dialect.SQLType("applied_at", reflect.TypeOf(time.Time{}))
+	statement := fmt.Sprintf("CREATE TABLE %s (version %s PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at %s NOT NULL)", table, versionType, timeType)
+	migrator.Logger.Infof("Creating the migrations table %s", migrator.Table)
+	if _, err := migrator.DB.ExecContext(ctx, statement); err != nil {
+		return errors.CreationFailed.With("table", migrator.Table).Wrap(err)
+	}
+	return nil
+}
@@ -0,0 +1,488 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	_ "github.com/proullon/ramsql/driver"
+	"github.com/stretchr/testify/suite"
+)
+
+type MigrationSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+func TestMigrationSuite(t *testing.T) {
+	suite.Run(t, new(MigrationSuite))
+}
+
+func exec(statement string) sql.MigrationFunc {
+	return func(ctx context.Context, runner sql.Runner) error {
+		_, err := runner.ExecContext(ctx, statement)
+		return err
+	}
+}
+
+func personMigrations() []sql.Migration {
+	return []sql.Migration{
+		{
+			Version: 1,
+			Name:    "create persons",
+			Up:      func(ctx context.Context, runner sql.Runner) error { return runner.CreateTableContext(ctx, Person{}) },
+			Down:    func(ctx context.Context, runner sql.Runner) error { return runner.DeleteTableContext(ctx, Person{}) },
+		},
+		{
+			Version: 2,
+			Name:    "create animals",
+			Up:      exec("CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)"),
+			Down:    exec("DROP TABLE animal"),
+		},
+		{
+			Version: 3,
+			Name:    "create plants",
+			Up:      exec("CREATE TABLE plant (id VARCHAR(80) PRIMARY KEY)"),
+			Down:    exec("DROP TABLE plant"),
+		},
+	}
+}
+
+// appliedMigrations makes the recorder describe the migrations table, with the given versions applied
+func appliedMigrations(name string, versions ...int64) {
+	describeTable(name, sql.PostgresDialect{}, sql.DefaultMigrationsTable, "version INT8", "name VARCHAR", "applied_at TIMESTAMP")
+	rows := make([][]driver.Value, 0, len(versions))
+	for _, version := range versions {
+		rows = append(rows, []driver.Value{version, fmt.Sprintf("migration %d", version), time.Now().UTC()})
+	}
+	Respond(name, "SELECT version, name, applied_at FROM schema_migrations ORDER BY version", []string{"version INT8", "name VARCHAR", "applied_at TIMESTAMP"}, rows...)
+}
+
+// migrationStatements returns the statements recorded for the given data source name without those of the Lock
+func migrationStatements(name string) []string {
+	statements := []string{}
+	for _, statement := range Recorded(name) {
+		if !strings.Contains(statement, "schema_locks") {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+func (suite *MigrationSuite) TestCanMigrate() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries(sql.DefaultMigrationsTable)
+
+	migrator, err := sql.NewMigrator(db, personMigrations()...)
+	suite.Require().Nil(err, "Failed to create the migrator")
+	suite.Require().Nil(migrator.Migrate(ctx), "Failed to migrate")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"CREATE TABLE schema_migrations (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL)",
+		"SELECT version, name, applied_at FROM schema_migrations ORDER BY version",
+		"BEGIN",
+		"CREATE TABLE person (id VARCHAR(80) PRIMARY KEY, name VARCHAR(80) NOT NULL, age INT NOT NULL)",
+		"CREATE INDEX person_name_idx ON person (name)",
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		"COMMIT",
+		"BEGIN",
+		"CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)",
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		"COMMIT",
+		"BEGIN",
+		"CREATE TABLE plant (id VARCHAR(80) PRIMARY KEY)",
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		"COMMIT",
+	}, migrationStatements(name))
+
+	appliedMigrations(name, 1, 2, 3)
+	suite.Require().Nil(migrator.Migrate(ctx), "Migrating again should do nothing")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"SELECT version, name, applied_at FROM schema_migrations ORDER BY version",
+	}, migrationStatements(name)[16:])
+
+	statuses, err := migrator.Status(ctx)
+	suite.Require().Nil(err, "Failed to get the status of the migrations")
+	suite.Require().Len(statuses, 3)
+	suite.Assert().Equal("create persons", statuses[0].Name)
+	suite.Assert().True(statuses[2].Applied, "The migration should be applied")
+	suite.Assert().False(statuses[0].AppliedAt.IsZero(), "The migration should have an applied time")
+}
+
+func (suite *MigrationSuite) TestCanMigrateToVersionAndRollback() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	migrator, err := sql.NewMigrator(db, personMigrations()...)
+	suite.Require().Nil(err, "Failed to create the migrator")
+
+	appliedMigrations(name, 1)
+	suite.Require().Nil(migrator.MigrateTo(ctx, 2), "Failed to migrate to version 2")
+	appliedMigrations(name, 1, 2)
+	suite.Require().Nil(migrator.Rollback(ctx, 1), "Failed to rollback")
+	appliedMigrations(name, 1, 2, 3)
+	suite.Require().Nil(migrator.MigrateTo(ctx, 1), "Failed to migrate down to version 1")
+	appliedMigrations(name, 1)
+	suite.Require().Nil(migrator.Rollback(ctx, 10), "Failed to rollback everything")
+
+	statements := []string{}
+	for _, statement := range migrationStatements(name) {
+		if !strings.HasPrefix(statement, "SELECT") && statement != "BEGIN" && statement != "COMMIT" {
+			statements = append(statements, statement)
+		}
+	}
+	suite.Assert().Equal([]string{
+		"CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)",
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		"DROP TABLE animal",
+		"DELETE FROM schema_migrations WHERE version = $1",
+		"DROP TABLE plant",
+		"DELETE FROM schema_migrations WHERE version = $1",
+		"DROP TABLE animal",
+		"DELETE FROM schema_migrations WHERE version = $1",
+		"DROP TABLE person",
+		"DELETE FROM schema_migrations WHERE version = $1",
+	}, statements)
+}
+
+func (suite *MigrationSuite) TestShouldRollbackFailedMigration() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	appliedMigrations(name)
+
+	migrator, err := sql.NewMigrator(db, sql.Migration{
+		Version: 1,
+		Name:    "broken",
+		Up: func(ctx context.Context, runner sql.Runner) error {
+			if _, err := runner.ExecContext(ctx, "CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)"); err != nil {
+				return err
+			}
+			return errors.RuntimeError.WithStack()
+		},
+	})
+	suite.Require().Nil(err, "Failed to create the migrator")
+	err = migrator.Migrate(ctx)
+	suite.Require().NotNil(err, "The migration should fail")
+	suite.Assert().Truef(errors.Is(err, errors.RuntimeError), "Error should be a RuntimeError, was: %s", err)
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		"CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)",
+		"ROLLBACK",
+	}, migrationStatements(name)[2:], "The failed migration should have been rolled back")
+}
+
+func (suite *MigrationSuite) TestShouldNotCreateMigrationsTableThatCannotBeRead() {
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries(sql.DefaultMigrationsTable)
+	Fail(name, catalog.Columns, fmt.Errorf("permission denied for schema public"))
+
+	migrator, err := sql.NewMigrator(db, personMigrations()...)
+	suite.Require().Nil(err, "Failed to create the migrator")
+	err = migrator.Migrate(context.Background())
+	suite.Require().NotNil(err, "Should not migrate when the migrations table cannot be read")
+	suite.Assert().Contains(err.Error(), "permission denied")
+	suite.Assert().Equal([]string{catalog.Columns}, migrationStatements(name), "The migrations table should not be created")
+}
+
+func (suite *MigrationSuite) TestCanUseOtherMigrationsTable() {
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	describeTable(name, sql.PostgresDialect{}, "ops_migrations", "version INT8", "name VARCHAR", "applied_at TIMESTAMP")
+
+	migrator, err := sql.NewMigrator(db, sql.Migration{Version: 1, Name: "animals", Up: exec("CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)")})
+	suite.Require().Nil(err, "Failed to create the migrator")
+	migrator.Table = "ops_migrations"
+	suite.Require().Nil(migrator.Migrate(context.Background()), "Failed to migrate")
+	suite.Assert().Equal("ops_migrations", migrator.Lock.Name, "The lock should be named after the migrations table")
+	suite.Assert().Equal([]string{
+		"SELECT version, name, applied_at FROM ops_migrations ORDER BY version",
+		"BEGIN",
+		"CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)",
+		"INSERT INTO ops_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		"COMMIT",
+	}, migrationStatements(name)[1:])
+}
+
+func (suite *MigrationSuite) TestCanLoadMigrationsFromFS() {
+	fsys := fstest.MapFS{
+		"migrations/0001_create_animals.up.sql":   {Data: []byte("CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)")},
+		"migrations/0001_create_animals.down.sql": {Data: []byte("DROP TABLE animal")},
+		"migrations/0002_add_plants.up.sql":       {Data: []byte("CREATE TABLE plant (id VARCHAR(80) PRIMARY KEY)")},
+		"migrations/README.md":                    {Data: []byte("Migrations")},
+	}
+	migrations, err := sql.LoadMigrations(fsys, "migrations")
+	suite.Require().Nil(err, "Failed to load migrations")
+	suite.Require().Len(migrations, 2)
+	suite.Assert().Equal(int64(1), migrations[0].Version)
+	suite.Assert().Equal("create_animals", migrations[0].Name)
+	suite.Assert().NotNil(migrations[0].Down)
+	suite.Assert().Equal(int64(2), migrations[1].Version)
+	suite.Assert().Nil(migrations[1].Down)
+
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	appliedMigrations(name)
+	migrator, err := sql.NewMigrator(db, migrations...)
+	suite.Require().Nil(err, "Failed to create the migrator")
+	suite.Require().Nil(migrator.Migrate(ctx), "Failed to migrate")
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		"CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)",
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		"COMMIT",
+		"BEGIN",
+		"CREATE TABLE plant (id VARCHAR(80) PRIMARY KEY)",
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3)",
+		"COMMIT",
+	}, migrationStatements(name)[2:])
+
+	appliedMigrations(name, 1, 2)
+	err = migrator.Rollback(ctx, 1)
+	suite.Require().NotNil(err, "Should not rollback a migration without down step")
+	suite.Assert().Truef(errors.Is(err, errors.Unsupported), "Error should be an Unsupported, was: %s", err)
+}
+
+func (suite *MigrationSuite) TestShouldNotLoadInvalidMigrations() {
+	_, err := sql.LoadMigrations(fstest.MapFS{"migrations/first.up.sql": {Data: []byte("SELECT 1")}}, "migrations")
+	suite.Require().NotNil(err, "Should not load a migration without a version")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+
+	_, err = sql.LoadMigrations(fstest.MapFS{"migrations/0001_first.down.sql": {Data: []byte("SELECT 1")}}, "migrations")
+	suite.Require().NotNil(err, "Should not load a migration without an up step")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+}
+
+func (suite *MigrationSuite) TestShouldNotAddDuplicateMigrations() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	_, err = sql.NewMigrator(db, sql.Migration{Version: 1, Up: exec("SELECT 1")}, sql.Migration{Version: 1, Up: exec("SELECT 2")})
+	suite.Require().NotNil(err, "Should not add two migrations with the same version")
+	suite.Assert().Truef(errors.Is(err, errors.DuplicateFound), "Error should be a DuplicateFound, was: %s", err)
+
+	_, err = sql.NewMigrator(db, sql.Migration{Version: 0, Up: exec("SELECT 1")})
+	suite.Require().NotNil(err, "Should not add a migration with version 0")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+}
+
+func (suite *MigrationSuite) TestShouldNotUseTransactionsWithoutTransactionalDDL() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.MySQLDialect{}
+	mysqlCatalog, _ := sql.MySQLDialect{}.DescribeQueries(sql.DefaultMigrationsTable)
+
+	migrator, err := sql.NewMigrator(db, sql.Migration{Version: 1, Name: "animals", Up: exec("CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)")})
+	suite.Require().Nil(err, "Failed to create the migrator")
+	suite.Require().Nil(migrator.Migrate(context.Background()), "Failed to migrate")
+	suite.Assert().Equal([]string{
+		"SELECT COUNT(*) FROM schema_locks",
+		"DELETE FROM schema_locks WHERE name = ? AND expires_at < ?",
+		"INSERT INTO schema_locks (name, owner, expires_at) VALUES (?, ?, ?)",
+		mysqlCatalog.Columns,
+		"CREATE TABLE schema_migrations (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at DATETIME(6) NOT NULL)",
+		"SELECT version, name, applied_at FROM schema_migrations ORDER BY version",
+		"CREATE TABLE animal (id VARCHAR(80) PRIMARY KEY)",
+		"INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)",
+		"DELETE FROM schema_locks WHERE name = ? AND owner = ?",
+	}, Recorded(suite.T().Name()), "The migration should not run in a transaction")
+}
+
+// describePerson sets the columns of the person table the recorder describes to the expand/contract helpers
+func describePerson(name string, columns ...string) {
+	describeTable(name, sql.PostgresDialect{}, "person", columns...)
+}
+
+func (suite *MigrationSuite) TestCanAddColumnWithBackfill() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries("person")
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age INT4")
+	Respond(name, "SELECT id FROM person WHERE nickname IS NULL ORDER BY id ASC LIMIT $1", []string{"id VARCHAR"}, []driver.Value{"01"}, []driver.Value{"02"})
+	Respond(name, "SELECT id FROM person WHERE nickname IS NULL AND id > $1 ORDER BY id ASC LIMIT $2", []string{"id VARCHAR"}, []driver.Value{"03"})
+
+	change := sql.ColumnChange{Table: "person", Column: "nickname", Type: "VARCHAR(80)", Using: "name", BatchSize: 2}
+	suite.Require().Nil(db.AddColumnWithBackfill(ctx, change), "Failed to add the column")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		"ALTER TABLE person ADD COLUMN nickname VARCHAR(80)",
+		"SELECT id FROM person WHERE nickname IS NULL ORDER BY id ASC LIMIT $1",
+		"UPDATE person SET nickname = name WHERE nickname IS NULL AND id <= $1",
+		"SELECT id FROM person WHERE nickname IS NULL AND id > $1 ORDER BY id ASC LIMIT $2",
+		"UPDATE person SET nickname = name WHERE nickname IS NULL AND id <= $1 AND id > $2",
+	}, Recorded(name), "The rows should be backfilled by batches of keys")
+
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age INT4", "nickname VARCHAR")
+	Respond(name, "SELECT id FROM person WHERE nickname IS NULL ORDER BY id ASC LIMIT $1", []string{"id VARCHAR"}, []driver.Value{"06"})
+	suite.Require().Nil(db.AddColumnWithBackfill(ctx, change), "Running the change again should resume it")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		"SELECT id FROM person WHERE nickname IS NULL ORDER BY id ASC LIMIT $1",
+		"UPDATE person SET nickname = name WHERE nickname IS NULL AND id <= $1",
+	}, Recorded(name)[7:], "The existing column should not be added again")
+
+	err = db.AddColumnWithBackfill(ctx, sql.ColumnChange{Table: "person", Column: "alias", Type: "VARCHAR(80)"})
+	suite.Require().NotNil(err, "A backfill needs a value")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+}
+
+func (suite *MigrationSuite) TestCanRenameColumn() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries("person")
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age INT4")
+
+	change := sql.ColumnChange{Table: "person", Column: "age", NewColumn: "years", Type: "INT", BatchSize: 2}
+	suite.Require().Nil(db.RenameColumn(ctx, change, sql.PhaseExpand), "Failed to expand")
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age INT4", "years INT4")
+	suite.Require().Nil(db.RenameColumn(ctx, change, sql.PhaseExpand), "Expanding again should only copy the rows")
+	suite.Require().Nil(db.RenameColumn(ctx, change, sql.PhaseContract), "Failed to contract")
+	describePerson(name, "id VARCHAR", "name VARCHAR", "years INT4")
+	suite.Require().Nil(db.RenameColumn(ctx, change, sql.PhaseContract), "Contracting again should do nothing")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		"ALTER TABLE person ADD COLUMN years INT",
+		"SELECT id FROM person WHERE years IS NULL ORDER BY id ASC LIMIT $1",
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		"SELECT id FROM person WHERE years IS NULL ORDER BY id ASC LIMIT $1",
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		"SELECT id FROM person WHERE years IS NULL ORDER BY id ASC LIMIT $1",
+		"BEGIN",
+		"ALTER TABLE person DROP COLUMN age",
+		"COMMIT",
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+	}, Recorded(name))
+}
+
+func (suite *MigrationSuite) TestCanChangeColumnType() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	catalog, _ := sql.PostgresDialect{}.DescribeQueries("person")
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age INT4")
+	Respond(name, "SELECT id FROM person WHERE age_new IS NULL ORDER BY id ASC LIMIT $1", []string{"id VARCHAR"}, []driver.Value{"01"}, []driver.Value{"02"})
+
+	change := sql.ColumnChange{Table: "person", Column: "age", Type: "VARCHAR(10)", Using: "CAST(age AS VARCHAR(10))"}
+	suite.Require().Nil(db.ChangeColumnType(ctx, change, sql.PhaseExpand), "Failed to expand")
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age INT4", "age_new VARCHAR")
+	Respond(name, "SELECT id FROM person WHERE age_new IS NULL ORDER BY id ASC LIMIT $1", []string{"id VARCHAR"})
+	suite.Require().Nil(db.ChangeColumnType(ctx, change, sql.PhaseContract), "Failed to contract")
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age VARCHAR")
+	suite.Require().Nil(db.ChangeColumnType(ctx, change, sql.PhaseContract), "Contracting again should do nothing")
+	suite.Require().Nil(db.ChangeColumnType(ctx, change, sql.PhaseExpand), "Expanding after contracting should do nothing")
+	suite.Assert().Equal([]string{
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		"ALTER TABLE person ADD COLUMN age_new VARCHAR(10)",
+		"SELECT id FROM person WHERE age_new IS NULL ORDER BY id ASC LIMIT $1",
+		"UPDATE person SET age_new = CAST(age AS VARCHAR(10)) WHERE age_new IS NULL AND id <= $1",
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		"SELECT id FROM person WHERE age_new IS NULL ORDER BY id ASC LIMIT $1",
+		"BEGIN",
+		"ALTER TABLE person DROP COLUMN age",
+		"ALTER TABLE person RENAME COLUMN age_new TO age",
+		"COMMIT",
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+		catalog.Columns,
+		"SELECT * FROM person WHERE 1 = 0",
+	}, Recorded(name))
+}
+
+func (suite *MigrationSuite) TestShouldNotChangeMissingColumn() {
+	ctx := context.Background()
+	name := suite.T().Name()
+	db, err := sql.Open("recorder", name, suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.RenameColumn(ctx, sql.ColumnChange{Table: "person", Column: "name", NewColumn: "fullname"}, sql.PhaseExpand)
+	suite.Require().NotNil(err, "The table does not exist")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+
+	describePerson(name, "id VARCHAR", "name VARCHAR", "age INT4")
+	err = db.ChangeColumnType(ctx, sql.ColumnChange{Table: "person", Column: "nickname", Type: "TEXT"}, sql.PhaseExpand)
+	suite.Require().NotNil(err, "The column does not exist")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+	err = db.RenameColumn(ctx, sql.ColumnChange{Table: "person", Column: "name"}, sql.PhaseExpand)
+	suite.Require().NotNil(err, "The new name is missing")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+}
+
+// Suite Tools
+
+func (suite *MigrationSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *MigrationSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *MigrationSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *MigrationSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
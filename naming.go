@@ -0,0 +1,90 @@
+package sql
+
+import (
+	"strings"
+	"unicode"
+)
+
+// TableNamer is implemented by schemas that choose the name of their SQL table
+//
+// TableName is called on the zero value of the schema, its result is used as is, the NamingStrategy of the DB does not apply to it
+type TableNamer interface {
+	TableName() string
+}
+
+// NamingStrategy tells how the SQL tables and columns of the schemas are named
+//
+// The zero value names the tables and columns after the lowercase name of their struct and field (OrderLine => orderline).
+//
+// Columns named with the column tag option keep their name.
+type NamingStrategy struct {
+	// SnakeCase names tables and columns in snake_case (OrderLine => order_line)
+	SnakeCase bool
+
+	// PluralTables names tables in the plural (order_line => order_lines)
+	PluralTables bool
+
+	// TablePrefix is added before the name of the tables (e.g.: "shop_")
+	TablePrefix string
+
+	// Namespace qualifies the tables with a database schema or namespace (e.g.: "sales" gives sales.order_lines)
+	Namespace string
+}
+
+// TableName returns the name of the table of the given struct name
+func (strategy NamingStrategy) TableName(name string) string {
+	name = strategy.name(name)
+	if strategy.PluralTables {
+		name = plural(name)
+	}
+	name = strategy.TablePrefix + name
+	if len(strategy.Namespace) > 0 {
+		name = strategy.Namespace + "." + name
+	}
+	return name
+}
+
+// ColumnName returns the name of the column of the given field name
+func (strategy NamingStrategy) ColumnName(name string) string {
+	return strategy.name(name)
+}
+
+func (strategy NamingStrategy) name(name string) string {
+	if strategy.SnakeCase {
+		return snakeCase(name)
+	}
+	return strings.ToLower(name)
+}
+
+// snakeCase converts a GO name in snake_case
+//
+// Acronyms are kept together: UserID => user_id, HTTPServer => http_server
+func snakeCase(name string) string {
+	runes := []rune(name)
+	result := strings.Builder{}
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				result.WriteRune('_')
+			}
+			result.WriteRune(unicode.ToLower(r))
+		} else {
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
+
+// plural returns the english plural of a name
+func plural(name string) string {
+	switch {
+	case len(name) == 0:
+		return name
+	case strings.HasSuffix(name, "s"), strings.HasSuffix(name, "x"), strings.HasSuffix(name, "z"), strings.HasSuffix(name, "ch"), strings.HasSuffix(name, "sh"):
+		return name + "es"
+	case strings.HasSuffix(name, "y") && len(name) > 1 && !strings.ContainsRune("aeiou", rune(name[len(name)-2])):
+		return name[:len(name)-1] + "ies"
+	default:
+		return name + "s"
+	}
+}
@@ -0,0 +1,128 @@
+package sql_test
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	"github.com/stretchr/testify/suite"
+)
+
+type NamingSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+type OrderLine struct {
+	ID        int    `sql:"key"`
+	ProductID string `sql:"index"`
+	UnitPrice int
+	Label     string `sql:"description"`
+}
+
+type LegacyOrder struct {
+	ID int `sql:"key"`
+}
+
+func (order LegacyOrder) TableName() string {
+	return "legacy.orders"
+}
+
+func TestNamingSuite(t *testing.T) {
+	suite.Run(t, new(NamingSuite))
+}
+
+func (suite *NamingSuite) TestCanNameTables() {
+	suite.Assert().Equal("orderline", sql.NamingStrategy{}.TableName("OrderLine"))
+	suite.Assert().Equal("order_line", sql.NamingStrategy{SnakeCase: true}.TableName("OrderLine"))
+	suite.Assert().Equal("order_lines", sql.NamingStrategy{SnakeCase: true, PluralTables: true}.TableName("OrderLine"))
+	suite.Assert().Equal("categories", sql.NamingStrategy{PluralTables: true}.TableName("Category"))
+	suite.Assert().Equal("boxes", sql.NamingStrategy{PluralTables: true}.TableName("Box"))
+	suite.Assert().Equal("keys", sql.NamingStrategy{PluralTables: true}.TableName("Key"))
+	suite.Assert().Equal("sales.shop_order_line", sql.NamingStrategy{SnakeCase: true, TablePrefix: "shop_", Namespace: "sales"}.TableName("OrderLine"))
+}
+
+func (suite *NamingSuite) TestCanNameColumns() {
+	suite.Assert().Equal("productid", sql.NamingStrategy{}.ColumnName("ProductID"))
+	suite.Assert().Equal("product_id", sql.NamingStrategy{SnakeCase: true}.ColumnName("ProductID"))
+	suite.Assert().Equal("http_server", sql.NamingStrategy{SnakeCase: true}.ColumnName("HTTPServer"))
+	suite.Assert().Equal("address2_line", sql.NamingStrategy{SnakeCase: true}.ColumnName("Address2Line"))
+}
+
+func (suite *NamingSuite) TestCanUseNamingStrategy() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Naming = sql.NamingStrategy{SnakeCase: true, PluralTables: true, Namespace: "sales"}
+
+	suite.Require().Nil(db.CreateTable(OrderLine{}), "Failed to create table")
+	suite.Require().Nil(db.Insert(OrderLine{1, "X12", 100, "Widget"}), "Failed to insert")
+	_, err = db.FindAll(OrderLine{}, sql.Queries{}.Add("product_id", "X12"))
+	suite.Require().Nil(err, "Failed to find")
+	suite.Require().Nil(db.UpdateAll(OrderLine{}, sql.Queries{}.Add("product_id", "X12").Add("unit_price", sql.QuerySet, 90)), "Failed to update")
+	suite.Require().Nil(db.DeleteAll(OrderLine{}, sql.Queries{}.Add("product_id", "X12")), "Failed to delete")
+	suite.Require().Nil(db.DeleteTable(OrderLine{}), "Failed to delete table")
+	suite.Assert().Equal([]string{
+		"CREATE TABLE sales.order_lines (id INT PRIMARY KEY, product_id VARCHAR(80) NOT NULL, unit_price INT NOT NULL, description VARCHAR(80) NOT NULL)",
+		"CREATE INDEX sales_order_lines_product_id_idx ON sales.order_lines (product_id)",
+		"INSERT INTO sales.order_lines (id, product_id, unit_price, description) VALUES ($1, $2, $3, $4)",
+		"SELECT id, product_id, unit_price, description FROM sales.order_lines WHERE product_id = $1",
+		"UPDATE sales.order_lines SET unit_price = $1 WHERE product_id = $2",
+		"DELETE FROM sales.order_lines WHERE product_id = $1",
+		"DROP TABLE sales.order_lines",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *NamingSuite) TestCanUseTableNamer() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Naming = sql.NamingStrategy{PluralTables: true, TablePrefix: "shop_"}
+
+	suite.Require().Nil(db.CreateTable(LegacyOrder{}), "Failed to create table")
+	suite.Require().Nil(db.Insert(LegacyOrder{12}), "Failed to insert")
+	suite.Assert().Equal([]string{
+		"CREATE TABLE legacy.orders (id INT PRIMARY KEY)",
+		"INSERT INTO legacy.orders (id) VALUES ($1)",
+	}, Recorded(suite.T().Name()), "The NamingStrategy should not apply to TableName")
+}
+
+// Suite Tools
+
+func (suite *NamingSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *NamingSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *NamingSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *NamingSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
@@ -0,0 +1,218 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// Page is a page of objects retrieved with keyset pagination
+type Page struct {
+	// Items contains the objects of the page
+	Items []interface{}
+
+	// Next is the cursor of the next page, it is empty if this page is the last one
+	Next string
+}
+
+// keyset contains the sort keys and the values of the last row of a page, decoded from a cursor
+type keyset struct {
+	Sorts  []Sort
+	Values []interface{}
+}
+
+// FindPage retrieves a page of objects of a schema that satisfy the queries with keyset pagination
+//
+// The page is sorted on the sort keys of the queries (see Queries.OrderBy), followed by the primary key of the schema.
+// The page size is given by Queries.Limit, and the page starts after the cursor given by Queries.After.
+//
+// The cursor is an opaque string that can be sent in the "cursor" URL parameter (see QueriesFromURL).
+//
+// The sort columns should not contain NULL values
+func (db *DB) FindPage(schema interface{}, queries Queries) (Page, error) {
+	return db.FindPageContext(context.Background(), schema, queries)
+}
+
+// FindPageContext retrieves a page of objects of a schema that satisfy the queries with keyset pagination
+//
+// If the context contains a transaction (see Tx.ToContext), the objects are retrieved within it
+func (db *DB) FindPageContext(ctx context.Context, schema interface{}, queries Queries) (Page, error) {
+	return db.session(ctx).findPage(ctx, schema, queries)
+}
+
+// FindPage retrieves a page of objects of a schema that satisfy the queries with keyset pagination within the transaction
+func (tx *Tx) FindPage(schema interface{}, queries Queries) (Page, error) {
+	return tx.FindPageContext(context.Background(), schema, queries)
+}
+
+// FindPageContext retrieves a page of objects of a schema that satisfy the queries with keyset pagination within the transaction
+func (tx *Tx) FindPageContext(ctx context.Context, schema interface{}, queries Queries) (Page, error) {
+	return tx.session().findPage(ctx, schema, queries)
+}
+
+// findPage retrieves a page of objects of a schema that satisfy the queries with keyset pagination
+func (session *session) findPage(ctx context.Context, schema interface{}, queries Queries) (Page, error) {
+	schemaType, _ := getTypeAndValue(schema)
+	info := getSchemaInfo(schemaType, session.Naming)
+
+	sorts, err := queries.Sorts()
+	if err != nil {
+		return Page{}, err
+	}
+	for _, key := range info.Keys() {
+		found := false
+		for _, sort := range sorts {
+			found = found || sort.Column == key.Column
+		}
+		if !found {
+			sorts = append(sorts, Sort{Column: key.Column})
+		}
+	}
+	if len(sorts) == 0 {
+		return Page{}, errors.ArgumentMissing.With("key").WithStack()
+	}
+	for _, sort := range sorts {
+		if _, found := info.Field(sort.Column); !found {
+			return Page{}, errors.ArgumentInvalid.With("sort", sort.Column).WithStack()
+		}
+	}
+
+	paged := Queries{}
+	for key, values := range queries {
+		paged[key] = values
+	}
+	delete(paged, offsetKey)
+	paged[orderByKey] = Query{queryOrderBy}
+	for _, sort := range sorts {
+		paged[orderByKey] = append(paged[orderByKey], sort)
+	}
+	limit, _, err := queries.Paging()
+	if err != nil {
+		return Page{}, err
+	}
+	if limit > 0 {
+		paged.Limit(limit + 1) // one more row tells if there is a next page
+	}
+	if values, found := queries[afterKey]; found {
+		if len(values) != 2 {
+			return Page{}, errors.ArgumentInvalid.With("cursor", values).WithStack()
+		}
+		cursor, _ := values[1].(string)
+		keys, err := decodeCursor(info, sorts, cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		paged[afterKey] = Query{queryAfter, keys}
+	}
+
+	items, err := session.findAll(ctx, schema, paged)
+	if err != nil {
+		return Page{}, err
+	}
+	page := Page{Items: items}
+	if limit > 0 && len(items) > limit {
+		page.Items = items[:limit]
+		if page.Next, err = encodeCursor(info, sorts, items[limit-1]); err != nil {
+			return Page{}, err
+		}
+	}
+	return page, nil
+}
+
+// encodeCursor encodes the values of the sort columns of a blob in a cursor
+func encodeCursor(info *schemaInfo, sorts []Sort, blob interface{}) (string, error) {
+	_, blobValue := getTypeAndValue(blob)
+	values := make([]interface{}, len(sorts))
+	for i, sort := range sorts {
+		field, _ := info.Field(sort.Column)
+		value, err := getColumnValue(field.StructField, field.Options, blobValue.Field(field.Index[0]))
+		if err != nil {
+			return "", err
+		}
+		values[i] = value
+	}
+	payload, err := json.Marshal(values)
+	if err != nil {
+		return "", errors.JSONMarshalError.Wrap(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+// decodeCursor decodes the values of the sort columns from a cursor
+func decodeCursor(info *schemaInfo, sorts []Sort, cursor string) (keyset, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return keyset{}, errors.ArgumentInvalid.With("cursor", cursor).WithStack()
+	}
+	raws := []json.RawMessage{}
+	if err = json.Unmarshal(payload, &raws); err != nil || len(raws) != len(sorts) {
+		return keyset{}, errors.ArgumentInvalid.With("cursor", cursor).WithStack()
+	}
+	keys := keyset{Sorts: sorts, Values: make([]interface{}, len(sorts))}
+	for i, sort := range sorts {
+		field, _ := info.Field(sort.Column)
+		if len(field.Options.ForeignKey) > 0 {
+			if keys.Values[i], err = decodeCursorValue(raws[i]); err != nil {
+				return keyset{}, errors.ArgumentInvalid.With("cursor", cursor).WithStack()
+			}
+			continue
+		}
+		value := reflect.New(field.Type)
+		if err = json.Unmarshal(raws[i], value.Interface()); err != nil {
+			return keyset{}, errors.ArgumentInvalid.With("cursor", cursor).WithStack()
+		}
+		keys.Values[i] = value.Elem().Interface()
+	}
+	return keys, nil
+}
+
+// decodeCursorValue decodes a cursor value whose GO type is not known (e.g.: the key of a foreign key)
+//
+// Integers are decoded as int64, so they keep their precision and their type, other numbers as float64
+func decodeCursorValue(raw json.RawMessage) (interface{}, error) {
+	var value interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	if number, ok := value.(json.Number); ok {
+		if integer, err := number.Int64(); err == nil {
+			return integer, nil
+		}
+		return number.Float64()
+	}
+	return value, nil
+}
+
+// whereClause builds the condition that selects the rows after the keyset
+//
+// For sort keys a, b, c, the condition is: (a > $1 OR (a = $1 AND b > $2) OR (a = $1 AND b = $2 AND c > $3))
+// where > becomes < for descending sort keys
+func (keys keyset) whereClause(dialect Dialect, parms []interface{}) (string, []interface{}) {
+	alternatives := []string{}
+	for i, sort := range keys.Sorts {
+		conditions := []string{}
+		for j := 0; j < i; j++ {
+			parms = append(parms, keys.Values[j])
+			conditions = append(conditions, dialect.Quote(keys.Sorts[j].Column)+" = "+dialect.Placeholder(len(parms)))
+		}
+		operator := QueryGreater
+		if sort.Descending {
+			operator = QueryLesser
+		}
+		parms = append(parms, keys.Values[i])
+		conditions = append(conditions, dialect.Quote(sort.Column)+" "+operator.String()+" "+dialect.Placeholder(len(parms)))
+		if len(conditions) > 1 {
+			alternatives = append(alternatives, "("+strings.Join(conditions, " AND ")+")")
+		} else {
+			alternatives = append(alternatives, conditions[0])
+		}
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", parms
+}
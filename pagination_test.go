@@ -0,0 +1,151 @@
+package sql_test
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	_ "github.com/proullon/ramsql/driver"
+	"github.com/stretchr/testify/suite"
+)
+
+type PaginationSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+func TestPaginationSuite(t *testing.T) {
+	suite.Run(t, new(PaginationSuite))
+}
+
+func (suite *PaginationSuite) TestCanFindPages() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1", "Doe", 18, nil}))
+	suite.Require().Nil(db.Insert(Person{"2", "Doe", 58, nil}))
+	suite.Require().Nil(db.Insert(Person{"3", "Doe", 32, nil}))
+	suite.Require().Nil(db.Insert(Person{"4", "Doe", 32, nil}))
+	suite.Require().Nil(db.Insert(Person{"5", "Doe", 25, nil}))
+
+	ids := []string{}
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		u, _ := url.Parse("https://www.acme.com/api/v1/persons?name=Doe&sort=-age&limit=2&cursor=" + url.QueryEscape(cursor))
+		page, err := db.FindPage(Person{}, sql.QueriesFromURL(u))
+		suite.Require().Nil(err, "Failed to find page %d", pages)
+		for _, item := range page.Items {
+			ids = append(ids, item.(*Person).ID)
+		}
+		if cursor = page.Next; len(cursor) == 0 {
+			break
+		}
+	}
+	suite.Assert().Equal([]string{"2", "3", "4", "5", "1"}, ids, "Pages should be sorted on age (descending) then id")
+}
+
+func (suite *PaginationSuite) TestCanFindAllInOnePage() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1", "Doe", 18, nil}))
+	suite.Require().Nil(db.Insert(Person{"2", "Doe", 58, nil}))
+
+	page, err := db.FindPage(Person{}, sql.Queries{}.Limit(2))
+	suite.Require().Nil(err, "Failed to find page")
+	suite.Assert().Len(page.Items, 2)
+	suite.Assert().Empty(page.Next, "There should be no next page")
+}
+
+func (suite *PaginationSuite) TestCanBuildKeysetStatement() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	u, _ := url.Parse("https://www.acme.com/api/v1/persons?sort=-age&limit=10&cursor=WzMyLCI0Il0")
+	_, err = db.FindPage(Person{}, sql.QueriesFromURL(u))
+	suite.Require().Nil(err, "Failed to find page")
+	suite.Assert().Equal([]string{
+		"SELECT id, name, age FROM person WHERE (age < $1 OR (age = $2 AND id > $3)) ORDER BY age DESC, id ASC LIMIT $4",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *PaginationSuite) TestCanDecodeForeignKeysInCursor() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	u, _ := url.Parse("https://www.acme.com/api/v1/members?sort=department_id&limit=10&cursor=WzkwMDcxOTkyNTQ3NDA5OTMsMV0")
+	_, err = db.FindPage(Member{}, sql.QueriesFromURL(u))
+	suite.Require().Nil(err, "Failed to find page")
+	suite.Assert().Equal([]string{
+		"SELECT id, name, department_id, mentor_id FROM member WHERE (department_id > $1 OR (department_id = $2 AND id > $3)) ORDER BY department_id ASC, id ASC LIMIT $4",
+	}, Recorded(suite.T().Name()))
+	parameters := Parameters(suite.T().Name())
+	suite.Require().Len(parameters, 1)
+	suite.Assert().Equal(int64(9007199254740993), parameters[0][0], "The foreign key should keep its integer type and precision")
+}
+
+func (suite *PaginationSuite) TestShouldNotFindPageWithInvalidCursor() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	_, err = db.FindPage(Person{}, sql.Queries{}.Limit(10).After("not a cursor"))
+	suite.Require().NotNil(err, "Should not find a page with an invalid cursor")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+}
+
+func (suite *PaginationSuite) TestShouldNotFindPageWithUnknownSortColumn() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	_, err = db.FindPage(Person{}, sql.Queries{}.OrderBy("height").Limit(10))
+	suite.Require().NotNil(err, "Should not find a page sorted on an unknown column")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+}
+
+// Suite Tools
+
+func (suite *PaginationSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *PaginationSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *PaginationSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *PaginationSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
+
+	"github.com/gildas/go-errors"
 )
 
 // Queries describes a map of Query objects
@@ -13,15 +17,53 @@ type Queries map[string]Query
 // Query describes a query in a Statement Where Clause
 type Query []interface{}
 
+// The keys of the Queries that are not part of the Where Clause start with "#"
+const (
+	orderByKey = "#orderby"
+	limitKey   = "#limit"
+	offsetKey  = "#offset"
+	afterKey   = "#after"
+	whereKey   = "#where"
+)
+
 // QueriesFromRequest creates a Queries from an HTTP Request
 func QueriesFromRequest(r *http.Request) Queries {
 	return QueriesFromURL(r.URL)
 }
 
 // QueriesFromURL creates Queries from a URL (from its query part)
+//
+// The parameters sort, limit, offset, and cursor are used to sort and page the results (see OrderBy, Limit, Offset, After).
+// The sort keys are separated by commas, e.g.: ?sort=-age,name&limit=10&offset=20
+//
+// The parameters that start with "#" are ignored, they would collide with the keys Queries uses internally
 func QueriesFromURL(u *url.URL) Queries {
 	queries := Queries{}
 	for key, values := range u.Query() {
+		if strings.HasPrefix(key, "#") {
+			continue
+		}
+		switch key {
+		case "sort":
+			for _, value := range values {
+				queries.OrderBy(strings.Split(value, ",")...)
+			}
+			continue
+		case "cursor":
+			queries.After(values[0])
+			continue
+		case "limit", "offset":
+			value, err := strconv.Atoi(values[0])
+			if err != nil {
+				continue
+			}
+			if key == "limit" {
+				queries.Limit(value)
+			} else {
+				queries.Offset(value)
+			}
+			continue
+		}
 		qvalues := make([]interface{}, len(values))
 		for i, value := range values {
 			qvalues[i] = value
@@ -35,10 +77,17 @@ func QueriesFromURL(u *url.URL) Queries {
 //
 // If the key is already present, values are added
 // If no values are given, the Queries is unchanged
+// If the key starts with "#" or the operator is not meant for a Where Clause, the Queries is unchanged
 func (queries Queries) Add(key string, values ...interface{}) Queries {
-	if len(values) == 0 {
+	if len(values) == 0 || strings.HasPrefix(key, "#") {
 		return queries
 	}
+	if operator, ok := values[0].(QueryOperator); ok {
+		switch operator {
+		case queryAfter, queryLimit, queryOffset, queryOrderBy:
+			return queries
+		}
+	}
 	if set, ok := values[0].(QueryOperator); ok && set.Operator == QuerySet.Operator {
 		key = "=" + key
 	}
@@ -60,27 +109,225 @@ func (queries Queries) Add(key string, values ...interface{}) Queries {
 	return queries
 }
 
+// OrderBy adds sort keys to the Queries
+//
+// The sort keys are parsed with ParseSort, e.g.: "name", "-age", "age DESC NULLS LAST".
+// Invalid sort keys are ignored
+func (queries Queries) OrderBy(keys ...string) Queries {
+	for _, key := range keys {
+		if sort, err := ParseSort(key); err == nil {
+			if _, found := queries[orderByKey]; !found {
+				queries[orderByKey] = Query{queryOrderBy}
+			}
+			queries[orderByKey] = append(queries[orderByKey], sort)
+		}
+	}
+	return queries
+}
+
+// Limit sets the maximum number of rows to retrieve
+//
+// A limit of 0 (or less) removes the limit
+func (queries Queries) Limit(limit int) Queries {
+	if limit <= 0 {
+		delete(queries, limitKey)
+		return queries
+	}
+	queries[limitKey] = Query{queryLimit, limit}
+	return queries
+}
+
+// Offset sets the number of rows to skip
+//
+// An offset of 0 (or less) removes the offset
+func (queries Queries) Offset(offset int) Queries {
+	if offset <= 0 {
+		delete(queries, offsetKey)
+		return queries
+	}
+	queries[offsetKey] = Query{queryOffset, offset}
+	return queries
+}
+
+// After sets the cursor after which the next page starts when using keyset pagination (see DB.FindPage)
+//
+// An empty cursor removes the cursor
+func (queries Queries) After(cursor string) Queries {
+	if len(cursor) == 0 {
+		delete(queries, afterKey)
+		return queries
+	}
+	queries[afterKey] = Query{queryAfter, cursor}
+	return queries
+}
+
+// Sorts returns the sort keys of the Queries
+//
+// If the Queries contain an invalid sort key, an errors.ArgumentInvalid is returned
+func (queries Queries) Sorts() ([]Sort, error) {
+	sorts := []Sort{}
+	if values, found := queries[orderByKey]; found && len(values) > 0 {
+		for _, value := range values[1:] {
+			sort, ok := value.(Sort)
+			if !ok {
+				return []Sort{}, errors.ArgumentInvalid.With("sort", value).WithStack()
+			}
+			sorts = append(sorts, sort)
+		}
+	}
+	return sorts, nil
+}
+
+// Paging returns the limit and the offset of the Queries (0 if not set)
+//
+// If the Queries contain an invalid limit or offset, an errors.ArgumentInvalid is returned
+func (queries Queries) Paging() (limit int, offset int, err error) {
+	if limit, err = queries.pagingValue(limitKey, "limit"); err != nil {
+		return 0, 0, err
+	}
+	if offset, err = queries.pagingValue(offsetKey, "offset"); err != nil {
+		return 0, 0, err
+	}
+	return limit, offset, nil
+}
+
+// pagingValue returns the value of the limit or offset stored with the given key (0 if not set)
+func (queries Queries) pagingValue(key, name string) (int, error) {
+	values, found := queries[key]
+	if !found {
+		return 0, nil
+	}
+	if len(values) != 2 {
+		return 0, errors.ArgumentInvalid.With(name, values).WithStack()
+	}
+	value, ok := values[1].(int)
+	if !ok {
+		return 0, errors.ArgumentInvalid.With(name, values[1]).WithStack()
+	}
+	return value, nil
+}
+
+// checkPaging checks the sort keys, the limit, and the offset of the Queries (see Sorts and Paging)
+func (queries Queries) checkPaging() error {
+	if _, err := queries.Sorts(); err != nil {
+		return err
+	}
+	_, _, err := queries.Paging()
+	return err
+}
+
+// OrderClauseWith builds the SQL ORDER BY, LIMIT, and OFFSET clauses for a Statement with the given Dialect
+//
+// The parameters of the clauses are appended to parms and numbered after them.
+// Invalid sort keys, limit, or offset are left out of the clauses (see Sorts and Paging)
+func (queries Queries) OrderClauseWith(dialect Dialect, parms []interface{}) (string, []interface{}) {
+	sorts, _ := queries.Sorts()
+	limit, offset, _ := queries.Paging()
+	return dialect.Paginate(sorts, limit, offset, parms)
+}
+
 // WhereClause builds the SQL Where Clause for a Statement
+//
+// The parameters are written with PostgreSQL placeholders ($1, $2, ...)
 func (queries Queries) WhereClause() (string, []interface{}) {
-	clause := strings.Builder{}
-	parms  := []interface{}{}
-	for column, values := range queries {
+	return queries.WhereClauseWith(PostgresDialect{}, []interface{}{})
+}
+
+// WhereClauseWith builds the SQL Where Clause for a Statement with the given Dialect
+//
+// The parameters of the clause are appended to parms and numbered after them
+func (queries Queries) WhereClauseWith(dialect Dialect, parms []interface{}) (string, []interface{}) {
+	terms, parms := queries.termsWith(dialect, parms)
+	return strings.Join(terms, " AND "), parms
+}
+
+// Match adds conditions to the Queries (see And, Or, Not)
+//
+// The conditions are added to the Where Clause with AND
+func (queries Queries) Match(conditions ...Condition) Queries {
+	for _, condition := range conditions {
+		if condition != nil {
+			queries[whereKey] = append(queries[whereKey], condition)
+		}
+	}
+	return queries
+}
+
+// termsWith builds the terms of the SQL Where Clause, they are meant to be joined with AND
+func (queries Queries) termsWith(dialect Dialect, parms []interface{}) ([]string, []interface{}) {
+	terms := []string{}
+	for _, column := range queries.keys(nil) {
+		values := queries[column]
+		if strings.HasPrefix(column, "#") {
+			switch column {
+			case afterKey:
+				if len(values) == 0 {
+					continue
+				}
+				if keys, ok := values[len(values)-1].(keyset); ok {
+					var term string
+					term, parms = keys.whereClause(dialect, parms)
+					terms = append(terms, term)
+				}
+			case whereKey:
+				for _, value := range values {
+					condition, ok := value.(Condition)
+					if !ok {
+						continue
+					}
+					var term string
+					term, _, parms = condition.conditionWith(dialect, parms)
+					if len(term) > 0 {
+						terms = append(terms, term)
+					}
+				}
+			}
+			// ignore ORDER BY, LIMIT, OFFSET (used by SelectStatement)
+			continue
+		}
 		operator, _ := values[0].(QueryOperator)
 		if operator.Operator == QueryIn.Operator {
 			args := []string{}
 			for _, value := range values[1:] {
 				parms = append(parms, value)
-				args  = append(args, fmt.Sprintf("$%d", len(parms)))
+				args  = append(args, dialect.Placeholder(len(parms)))
 			}
-			clause.WriteString(fmt.Sprintf(" AND %s %s (%s)", column, operator, strings.Join(args, ", ")))
+			terms = append(terms, fmt.Sprintf("%s %s (%s)", dialect.Quote(column), operator, strings.Join(args, ", ")))
 		} else {
 			if len(values) != operator.Arity || operator.Operator == QuerySet.Operator {
 				// ignore wrong # of arguments or SET Operator (used by UpdateStatement)
 				continue
 			}
 			parms = append(parms, values[1])
-			clause.WriteString(fmt.Sprintf(" AND %s %s $%d", column, operator, len(parms)))
+			terms = append(terms, fmt.Sprintf("%s %s %s", dialect.Quote(column), operator, dialect.Placeholder(len(parms))))
 		}
 	}
-	return strings.TrimPrefix(clause.String(), " AND "), parms
-}
\ No newline at end of file
+	return terms, parms
+}
+
+// keys returns the keys of the Queries in a stable order, so the statements built from them do not change from run to run
+//
+// The keys of the given columns come first in the order of the columns, then the other keys sorted alphabetically.
+// The keys that are not part of the Where Clause (ORDER BY, LIMIT, etc) come last
+func (queries Queries) keys(columns []string) []string {
+	keys := make([]string, 0, len(queries))
+	for key := range queries {
+		keys = append(keys, key)
+	}
+	ranks := map[string]int{}
+	for i, column := range columns {
+		ranks[column] = i + 1
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if special := strings.HasPrefix(keys[i], "#"); special != strings.HasPrefix(keys[j], "#") {
+			return !special
+		}
+		rankI := ranks[strings.TrimPrefix(keys[i], "=")]
+		rankJ := ranks[strings.TrimPrefix(keys[j], "=")]
+		if rankI != rankJ {
+			return rankJ == 0 || (rankI != 0 && rankI < rankJ)
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
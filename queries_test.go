@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gildas/go-errors"
 	"github.com/gildas/go-logger"
 	"github.com/gildas/go-sql"
 	"github.com/stretchr/testify/suite"
@@ -96,6 +97,118 @@ func (suite *QueriesTest) TestCanBuildWhereClause() {
 	suite.Assert().Len(parms, 7, "There should be 7 parameters")
 }
 
+func (suite *QueriesTest) TestCanCreateFromURLWithPaging() {
+	u, _ := url.Parse("https://www.acme.com/api/v1/persons?name=Doe&sort=-age,name&limit=10&offset=20")
+	queries := sql.QueriesFromURL(u)
+	suite.Require().NotNil(queries)
+	suite.Assert().Equal(sql.QueryEqual, queries["name"][0], "The operator for name should be Equal")
+	sorts, err := queries.Sorts()
+	suite.Require().Nil(err, "Failed to get the sort keys")
+	suite.Assert().Equal([]sql.Sort{{Column: "age", Descending: true}, {Column: "name"}}, sorts)
+	limit, offset, err := queries.Paging()
+	suite.Require().Nil(err, "Failed to get the paging")
+	suite.Assert().Equal(10, limit)
+	suite.Assert().Equal(20, offset)
+	where, parms := queries.WhereClause()
+	suite.Assert().Equal("name = $1", where, "Sorting and paging should not be in the Where Clause")
+	suite.Assert().Len(parms, 1, "There should be 1 parameter")
+}
+
+func (suite *QueriesTest) TestShouldIgnoreInvalidPaging() {
+	u, _ := url.Parse("https://www.acme.com/api/v1/persons?sort=age+sideways&limit=ten&offset=-1")
+	queries := sql.QueriesFromURL(u)
+	suite.Assert().Len(queries, 0, "Invalid sort keys, limit, and offset should be ignored")
+}
+
+func (suite *QueriesTest) TestShouldIgnoreInternalKeysFromURL() {
+	u, _ := url.Parse("https://www.acme.com/api/v1/persons?name=Doe&%23orderby=x&%23limit=abc&%23offset=1&%23after=z")
+	queries := sql.QueriesFromURL(u)
+	suite.Assert().Len(queries, 1, "The parameters starting with # should be ignored")
+	_, err := queries.Sorts()
+	suite.Assert().Nil(err)
+	_, _, err = queries.Paging()
+	suite.Assert().Nil(err)
+}
+
+func (suite *QueriesTest) TestShouldNotPanicWithInvalidPaging() {
+	queries := sql.Queries{"#orderby": sql.Query{sql.QueryEqual, "x"}, "#limit": sql.Query{sql.QueryEqual, "abc"}}
+	_, err := queries.Sorts()
+	suite.Require().NotNil(err, "An invalid sort key should fail")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	_, _, err = queries.Paging()
+	suite.Require().NotNil(err, "An invalid limit should fail")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	clause, _ := queries.OrderClauseWith(sql.PostgresDialect{}, []interface{}{})
+	suite.Assert().Empty(clause, "Invalid paging should be left out")
+}
+
+func (suite *QueriesTest) TestShouldNotAddInternalKeys() {
+	queries := sql.Queries{}.Add("#where", 1).Add("#limit", 10).Add("name", "Doe")
+	suite.Assert().Len(queries, 1, "The keys starting with # should not be added")
+	where, parms := sql.Queries{"#where": sql.Query{sql.QueryEqual, 1}, "#after": sql.Query{}}.WhereClause()
+	suite.Assert().Empty(where, "Invalid conditions should be ignored")
+	suite.Assert().Empty(parms)
+}
+
+func (suite *QueriesTest) TestCanParseSort() {
+	sort, err := sql.ParseSort("age")
+	suite.Require().Nil(err)
+	suite.Assert().Equal(sql.Sort{Column: "age"}, sort)
+	sort, err = sql.ParseSort("-age")
+	suite.Require().Nil(err)
+	suite.Assert().Equal(sql.Sort{Column: "age", Descending: true}, sort)
+	sort, err = sql.ParseSort("age desc nulls first")
+	suite.Require().Nil(err)
+	suite.Assert().Equal(sql.Sort{Column: "age", Descending: true, Nulls: sql.NullsFirst}, sort)
+	suite.Assert().Equal("age DESC NULLS FIRST", sort.String())
+	sort, err = sql.ParseSort("+age NULLS LAST")
+	suite.Require().Nil(err)
+	suite.Assert().Equal(sql.Sort{Column: "age", Nulls: sql.NullsLast}, sort)
+	_, err = sql.ParseSort("age nulls")
+	suite.Assert().NotNil(err, "Should not parse an incomplete NULLS")
+	_, err = sql.ParseSort("-")
+	suite.Assert().NotNil(err, "Should not parse a sort key without a column")
+	_, err = sql.ParseSort("  ")
+	suite.Assert().NotNil(err, "Should not parse an empty sort key")
+}
+
+func (suite *QueriesTest) TestCanBuildWhereClauseWithConditions() {
+	where, parms := sql.Queries{}.Match(sql.Or(sql.Where("status", "open"), sql.Where("priority", sql.QueryGreater, 3))).WhereClause()
+	suite.Assert().Equal("(status = $1 OR priority > $2)", where)
+	suite.Assert().Equal([]interface{}{"open", 3}, parms)
+
+	where, parms = sql.Queries{}.Match(sql.And(
+		sql.Where("a", 1),
+		sql.Or(sql.Where("b", 2), sql.Not(sql.Where("c", sql.QueryLike, "x%"))),
+	)).WhereClause()
+	suite.Assert().Equal("(a = $1 AND (b = $2 OR NOT (c LIKE $3)))", where)
+	suite.Assert().Equal([]interface{}{1, 2, "x%"}, parms)
+
+	where, parms = sql.Queries{}.Match(sql.Not(sql.Or(sql.Where("a", 1), sql.Where("b", 2, 3)))).WhereClause()
+	suite.Assert().Equal("NOT (a = $1 OR b IN ($2, $3))", where)
+	suite.Assert().Equal([]interface{}{1, 2, 3}, parms)
+
+	where, parms = sql.Queries{}.Match(sql.Or(sql.Where("a", 1), sql.Queries{}.Add("b", 2).Add("c", 3))).WhereClause()
+	suite.Assert().Equal("(a = $1 OR (b = $2 AND c = $3))", where)
+	suite.Assert().Equal([]interface{}{1, 2, 3}, parms)
+}
+
+func (suite *QueriesTest) TestCanCombineQueriesAndConditions() {
+	queries := sql.Queries{}.Add("age", sql.QueryGreater, 18).Match(sql.Or(sql.Where("name", "Doe"), sql.Where("name", "Smith")))
+	where, parms := queries.WhereClauseWith(sql.SQLiteDialect{}, []interface{}{})
+	suite.Assert().Equal("age > ? AND (name = ? OR name = ?)", where)
+	suite.Assert().Equal([]interface{}{18, "Doe", "Smith"}, parms)
+}
+
+func (suite *QueriesTest) TestShouldIgnoreEmptyConditions() {
+	where, parms := sql.Queries{}.Match(sql.Or(), sql.And(sql.Or()), sql.Not(sql.And()), nil).WhereClause()
+	suite.Assert().Empty(where)
+	suite.Assert().Empty(parms)
+
+	where, _ = sql.Queries{}.Match(sql.Or(sql.Where("a", 1), sql.And())).WhereClause()
+	suite.Assert().Equal("a = $1", where, "Single conditions should not be grouped")
+}
+
 // Suite Tools
 
 func (suite *QueriesTest) SetupSuite() {
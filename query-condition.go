@@ -0,0 +1,102 @@
+package sql
+
+import "strings"
+
+// Condition describes a condition of a Where Clause
+//
+// Conditions are combined with And, Or, and Not, and added to Queries with Queries.Match.
+// Queries are Conditions too, their queries are combined with AND.
+//
+// Example:
+//
+//	queries := sql.Queries{}.Match(sql.Or(
+//		sql.Where("status", "open"),
+//		sql.Not(sql.Where("priority", sql.QueryLesserOrEqual, 3)),
+//	))
+type Condition interface {
+	// conditionWith builds the condition with the given Dialect, its parameters are appended to parms
+	//
+	// grouped tells if the condition is enclosed in parenthesis
+	conditionWith(dialect Dialect, parms []interface{}) (clause string, grouped bool, _ []interface{})
+}
+
+type andCondition []Condition
+type orCondition []Condition
+type notCondition struct {
+	Condition
+}
+
+// Where creates a Condition on a column
+//
+// The values are given like in Queries.Add, e.g.: Where("age", sql.QueryGreater, 18)
+func Where(column string, values ...interface{}) Condition {
+	return Queries{}.Add(column, values...)
+}
+
+// And creates a Condition that is satisfied when all the given Conditions are
+func And(conditions ...Condition) Condition {
+	return andCondition(conditions)
+}
+
+// Or creates a Condition that is satisfied when at least one of the given Conditions is
+func Or(conditions ...Condition) Condition {
+	return orCondition(conditions)
+}
+
+// Not creates a Condition that is satisfied when the given Condition is not
+func Not(condition Condition) Condition {
+	return notCondition{condition}
+}
+
+func (queries Queries) conditionWith(dialect Dialect, parms []interface{}) (string, bool, []interface{}) {
+	terms, parms := queries.termsWith(dialect, parms)
+	return group(terms, " AND "), len(terms) > 1, parms
+}
+
+func (condition andCondition) conditionWith(dialect Dialect, parms []interface{}) (string, bool, []interface{}) {
+	terms, parms := conditionsWith(condition, dialect, parms)
+	return group(terms, " AND "), len(terms) > 1, parms
+}
+
+func (condition orCondition) conditionWith(dialect Dialect, parms []interface{}) (string, bool, []interface{}) {
+	terms, parms := conditionsWith(condition, dialect, parms)
+	return group(terms, " OR "), len(terms) > 1, parms
+}
+
+func (condition notCondition) conditionWith(dialect Dialect, parms []interface{}) (string, bool, []interface{}) {
+	if condition.Condition == nil {
+		return "", false, parms
+	}
+	clause, grouped, parms := condition.Condition.conditionWith(dialect, parms)
+	if len(clause) == 0 {
+		return "", false, parms
+	}
+	if !grouped {
+		clause = "(" + clause + ")"
+	}
+	return "NOT " + clause, false, parms
+}
+
+// conditionsWith builds the non empty conditions with the given Dialect
+func conditionsWith(conditions []Condition, dialect Dialect, parms []interface{}) ([]string, []interface{}) {
+	terms := []string{}
+	for _, condition := range conditions {
+		if condition == nil {
+			continue
+		}
+		var term string
+		term, _, parms = condition.conditionWith(dialect, parms)
+		if len(term) > 0 {
+			terms = append(terms, term)
+		}
+	}
+	return terms, parms
+}
+
+// group joins the terms with the given operator, enclosed in parenthesis if there is more than one term
+func group(terms []string, operator string) string {
+	if len(terms) > 1 {
+		return "(" + strings.Join(terms, operator) + ")"
+	}
+	return strings.Join(terms, operator)
+}
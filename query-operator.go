@@ -21,6 +21,14 @@ var (
 	QuerySet            = QueryOperator{"SET", 2}
 )
 
+// The operators of the keys that are not part of the Where Clause, they cannot be used with Queries.Add
+var (
+	queryAfter   = QueryOperator{"AFTER", 2}
+	queryLimit   = QueryOperator{"LIMIT", 2}
+	queryOffset  = QueryOperator{"OFFSET", 2}
+	queryOrderBy = QueryOperator{"ORDER BY", math.MaxInt32}
+)
+
 // String returns a string representation of the operator
 func (operator QueryOperator) String() string {
 	return operator.Operator
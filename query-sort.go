@@ -0,0 +1,92 @@
+package sql
+
+import (
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// Sort describes how the rows of a SELECT statement are sorted on a column
+type Sort struct {
+	Column     string
+	Descending bool
+	Nulls      NullsOrder
+}
+
+// NullsOrder describes where NULL values go when sorting
+type NullsOrder int
+
+const (
+	// NullsDefault lets the database decide where NULL values go
+	NullsDefault NullsOrder = iota
+	// NullsFirst puts NULL values before the other values
+	NullsFirst
+	// NullsLast puts NULL values after the other values
+	NullsLast
+)
+
+// ParseSort parses a sort key
+//
+// A sort key is a column name, optionally prefixed with "-" (descending) or "+" (ascending),
+// optionally followed by ASC or DESC, then NULLS FIRST or NULLS LAST.
+//
+// Examples: "name", "-age", "age DESC NULLS LAST"
+func ParseSort(key string) (Sort, error) {
+	words := strings.Fields(key)
+	if len(words) == 0 {
+		return Sort{}, errors.ArgumentMissing.With("sort").WithStack()
+	}
+	sort := Sort{Column: words[0]}
+	if strings.HasPrefix(sort.Column, "-") {
+		sort.Column = sort.Column[1:]
+		sort.Descending = true
+	} else if strings.HasPrefix(sort.Column, "+") {
+		sort.Column = sort.Column[1:]
+	}
+	if len(sort.Column) == 0 {
+		return Sort{}, errors.ArgumentInvalid.With("sort", key).WithStack()
+	}
+	words = words[1:]
+	if len(words) > 0 {
+		switch strings.ToUpper(words[0]) {
+		case "ASC":
+			sort.Descending = false
+			words = words[1:]
+		case "DESC":
+			sort.Descending = true
+			words = words[1:]
+		}
+	}
+	if len(words) > 0 {
+		if len(words) != 2 || strings.ToUpper(words[0]) != "NULLS" {
+			return Sort{}, errors.ArgumentInvalid.With("sort", key).WithStack()
+		}
+		switch strings.ToUpper(words[1]) {
+		case "FIRST":
+			sort.Nulls = NullsFirst
+		case "LAST":
+			sort.Nulls = NullsLast
+		default:
+			return Sort{}, errors.ArgumentInvalid.With("sort", key).WithStack()
+		}
+	}
+	return sort, nil
+}
+
+// String returns a string representation of the sort key
+func (sort Sort) String() string {
+	sb := strings.Builder{}
+	sb.WriteString(sort.Column)
+	if sort.Descending {
+		sb.WriteString(" DESC")
+	} else {
+		sb.WriteString(" ASC")
+	}
+	switch sort.Nulls {
+	case NullsFirst:
+		sb.WriteString(" NULLS FIRST")
+	case NullsLast:
+		sb.WriteString(" NULLS LAST")
+	}
+	return sb.String()
+}
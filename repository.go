@@ -0,0 +1,117 @@
+package sql
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/gildas/go-errors"
+)
+
+// Repository gives typed access to the SQL table of a GO struct type
+//
+// The table and column metadata of T are named with the NamingStrategy of the DB,
+// they are worked out the first time T is used and cached, like for the other structured operations of the DB.
+//
+// All methods run in the transaction stored in the context if any (see Tx.ToContext)
+type Repository[T any] struct {
+	DB *DB
+}
+
+// NewRepository creates a new Repository for the given struct type
+//
+// T must be a struct type, otherwise an errors.ArgumentInvalid is returned
+func NewRepository[T any](db *DB) (*Repository[T], error) {
+	schemaType := reflect.TypeOf((*T)(nil)).Elem()
+	if schemaType.Kind() != reflect.Struct {
+		return nil, errors.ArgumentInvalid.With("T", schemaType.String()).WithStack()
+	}
+	return &Repository[T]{DB: db}, nil
+}
+
+// Table returns the name of the SQL table of the Repository
+func (repository Repository[T]) Table() string {
+	return getSchemaInfo(reflect.TypeOf((*T)(nil)).Elem(), repository.DB.Naming).Table
+}
+
+// FindAll retrieves all objects that satisfy the queries
+func (repository Repository[T]) FindAll(ctx context.Context, queries Queries) ([]T, error) {
+	blobs, err := repository.DB.session(ctx).findAll(ctx, new(T), queries)
+	if err != nil {
+		return []T{}, err
+	}
+	results := make([]T, 0, len(blobs))
+	for _, blob := range blobs {
+		results = append(results, *blob.(*T))
+	}
+	return results, nil
+}
+
+// Find retrieves the first object that satisfies the queries
+//
+// If no object satisfies the queries, an errors.NotFound is returned
+func (repository Repository[T]) Find(ctx context.Context, queries Queries) (T, error) {
+	var result T
+	blob, err := repository.DB.session(ctx).find(ctx, new(T), queries)
+	if err != nil {
+		return result, err
+	}
+	return *blob.(*T), nil
+}
+
+// FindPage retrieves a page of objects that satisfy the queries with keyset pagination
+//
+// It returns the objects of the page and the cursor of the next page (empty if this page is the last one), see DB.FindPage
+func (repository Repository[T]) FindPage(ctx context.Context, queries Queries) ([]T, string, error) {
+	page, err := repository.DB.session(ctx).findPage(ctx, new(T), queries)
+	if err != nil {
+		return []T{}, "", err
+	}
+	results := make([]T, 0, len(page.Items))
+	for _, blob := range page.Items {
+		results = append(results, *blob.(*T))
+	}
+	return results, page.Next, nil
+}
+
+// Insert inserts an object in the SQL table
+//
+// The values generated by the database (autoincrement keys, defaults) are filled back into the object, see DB.Insert
+func (repository Repository[T]) Insert(ctx context.Context, blob *T) error {
+	if blob == nil {
+		return errors.ArgumentMissing.With("blob").WithStack()
+	}
+	return repository.DB.session(ctx).insert(ctx, blob)
+}
+
+// InsertMany inserts objects in the SQL table by batches, in one transaction
+func (repository Repository[T]) InsertMany(ctx context.Context, blobs []*T, options InsertManyOptions) error {
+	return repository.DB.InsertManyContext(ctx, blobs, options)
+}
+
+// Update updates an object in the SQL table
+//
+// The row is matched on the primary key of T, all other columns are updated
+func (repository Repository[T]) Update(ctx context.Context, blob T) error {
+	return repository.DB.session(ctx).update(ctx, &blob)
+}
+
+// Delete deletes an object from the SQL table
+//
+// The row is matched on the primary key of T
+func (repository Repository[T]) Delete(ctx context.Context, blob T) error {
+	return repository.DB.session(ctx).delete(ctx, &blob)
+}
+
+// Count counts the objects that satisfy the queries
+func (repository Repository[T]) Count(ctx context.Context, queries Queries) (int64, error) {
+	return repository.DB.session(ctx).count(ctx, new(T), queries)
+}
+
+// Exists tells if at least one object satisfies the queries
+func (repository Repository[T]) Exists(ctx context.Context, queries Queries) (bool, error) {
+	count, err := repository.Count(ctx, queries)
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,218 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	_ "github.com/proullon/ramsql/driver"
+	"github.com/stretchr/testify/suite"
+)
+
+type RepositorySuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+func TestRepositorySuite(t *testing.T) {
+	suite.Run(t, new(RepositorySuite))
+}
+
+func (suite *RepositorySuite) TestCanCreateRepository() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	persons, err := sql.NewRepository[Person](db)
+	suite.Require().Nil(err, "Failed to create repository")
+	suite.Assert().Equal("person", persons.Table())
+}
+
+func (suite *RepositorySuite) TestShouldNotCreateRepositoryForNonStruct() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	_, err = sql.NewRepository[string](db)
+	suite.Require().NotNil(err, "Should not create a repository for strings")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+}
+
+func (suite *RepositorySuite) TestCanInsertAndFind() {
+	ctx := context.Background()
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	persons, err := sql.NewRepository[Person](db)
+	suite.Require().Nil(err, "Failed to create repository")
+	suite.Require().Nil(persons.Insert(ctx, &Person{"1234", "Doe", 18, nil}))
+	suite.Require().Nil(persons.Insert(ctx, &Person{"5678", "Doe", 58, nil}))
+	suite.Require().Nil(persons.Insert(ctx, &Person{"9012", "Smith", 32, nil}))
+
+	found, err := persons.FindAll(ctx, sql.Queries{}.Add("name", "Doe"))
+	suite.Require().Nil(err, "Failed to find persons")
+	suite.Require().Len(found, 2)
+	suite.Assert().Equal("Doe", found[0].Name)
+	suite.Assert().Equal("Doe", found[1].Name)
+
+	person, err := persons.Find(ctx, sql.Queries{}.Add("id", "9012"))
+	suite.Require().Nil(err, "Failed to find person")
+	suite.Assert().Equal("Smith", person.Name)
+	suite.Assert().Equal(32, person.Age)
+
+	_, err = persons.Find(ctx, sql.Queries{}.Add("id", "0000"))
+	suite.Require().NotNil(err, "Should not find a person")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+}
+
+func (suite *RepositorySuite) TestCanInsertAndFillGeneratedFields() {
+	ctx := context.Background()
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	Respond(suite.T().Name(), "INSERT INTO ticket (title, status, priority, assignee, notes) VALUES ($1, $2, $3, $4, $5) RETURNING id, opened", []string{"id INT4", "opened TIMESTAMP"}, []driver.Value{int64(7), time.Now().UTC()})
+
+	tickets, err := sql.NewRepository[Ticket](db)
+	suite.Require().Nil(err, "Failed to create repository")
+	ticket := Ticket{Title: "Broken", Status: "open", Priority: 1}
+	suite.Require().Nil(tickets.Insert(ctx, &ticket), "Failed to insert")
+	suite.Assert().Equal(7, ticket.ID, "The generated key should be filled")
+	suite.Assert().NotNil(ticket.Opened, "The default should be filled")
+
+	err = tickets.Insert(ctx, nil)
+	suite.Require().NotNil(err, "Should not insert nil")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+
+	suite.Require().Nil(tickets.InsertMany(ctx, []*Ticket{{Title: "First", Status: "open", Priority: 1}, {Title: "Second", Status: "open", Priority: 2}}, sql.InsertManyOptions{}), "Failed to insert many")
+	err = tickets.InsertMany(ctx, []*Ticket{{Title: "First", Status: "open", Priority: 1}, nil}, sql.InsertManyOptions{})
+	suite.Require().NotNil(err, "Should not insert nil")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+}
+
+func (suite *RepositorySuite) TestCanUpdateAndDelete() {
+	ctx := context.Background()
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	persons, err := sql.NewRepository[Person](db)
+	suite.Require().Nil(err, "Failed to create repository")
+	suite.Require().Nil(persons.Insert(ctx, &Person{"1234", "Doe", 18, nil}))
+	suite.Require().Nil(persons.Insert(ctx, &Person{"5678", "Doe", 58, nil}))
+
+	suite.Require().Nil(persons.Update(ctx, Person{"1234", "Smith", 25, nil}), "Failed to update person")
+	person, err := persons.Find(ctx, sql.Queries{}.Add("id", "1234"))
+	suite.Require().Nil(err, "Failed to find person")
+	suite.Assert().Equal("Smith", person.Name)
+	suite.Assert().Equal(25, person.Age)
+
+	suite.Require().Nil(persons.Delete(ctx, person), "Failed to delete person")
+	found, err := persons.FindAll(ctx, sql.Queries{})
+	suite.Require().Nil(err, "Failed to find persons")
+	suite.Require().Len(found, 1)
+	suite.Assert().Equal("5678", found[0].ID)
+}
+
+func (suite *RepositorySuite) TestCanUpdateAndDeleteWithCompositeKey() {
+	ctx := context.Background()
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	memberships, err := sql.NewRepository[Membership](db)
+	suite.Require().Nil(err, "Failed to create repository")
+	suite.Require().Nil(memberships.Update(ctx, Membership{"core", 12, "owner"}), "Failed to update membership")
+	suite.Require().Nil(memberships.Delete(ctx, Membership{"core", 12, "owner"}), "Failed to delete membership")
+	suite.Assert().Equal([]string{
+		"UPDATE membership SET role = $1 WHERE member = $2 AND team = $3",
+		"DELETE FROM membership WHERE member = $1 AND team = $2",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *RepositorySuite) TestShouldNotUpdateWithoutKey() {
+	type Keyless struct {
+		Name string
+		Age  int
+	}
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	keyless, err := sql.NewRepository[Keyless](db)
+	suite.Require().Nil(err, "Failed to create repository")
+	err = keyless.Update(context.Background(), Keyless{"Doe", 18})
+	suite.Require().NotNil(err, "Should not update without a key")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+}
+
+func (suite *RepositorySuite) TestCanCount() {
+	ctx := context.Background()
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	persons, err := sql.NewRepository[Person](db)
+	suite.Require().Nil(err, "Failed to create repository")
+	suite.Require().Nil(persons.Insert(ctx, &Person{"1234", "Doe", 18, nil}))
+	suite.Require().Nil(persons.Insert(ctx, &Person{"5678", "Doe", 58, nil}))
+	suite.Require().Nil(persons.Insert(ctx, &Person{"9012", "Smith", 32, nil}))
+
+	count, err := persons.Count(ctx, sql.Queries{}.Add("name", "Doe"))
+	suite.Require().Nil(err, "Failed to count persons")
+	suite.Assert().Equal(int64(2), count)
+
+	exists, err := persons.Exists(ctx, sql.Queries{}.Add("name", "Smith"))
+	suite.Require().Nil(err, "Failed to check persons")
+	suite.Assert().True(exists)
+
+	exists, err = persons.Exists(ctx, sql.Queries{}.Add("name", "Nobody"))
+	suite.Require().Nil(err, "Failed to check persons")
+	suite.Assert().False(exists)
+}
+
+// Suite Tools
+
+func (suite *RepositorySuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *RepositorySuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *RepositorySuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *RepositorySuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
@@ -0,0 +1,191 @@
+package sql
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/gildas/go-errors"
+)
+
+// schemaInfo describes how a GO struct type is stored in an SQL table
+//
+// schemaInfo are worked out once per type and cached
+type schemaInfo struct {
+	Type    reflect.Type
+	Table   string
+	Fields  []schemaField
+	Columns []string
+	Indexes []Index
+}
+
+// schemaField describes how a field of a GO struct is stored in an SQL column
+type schemaField struct {
+	reflect.StructField
+	Column  string
+	Options fieldOptions
+}
+
+// schemaKey is the key of the schemaInfo cache, the names of the tables and columns depend on the NamingStrategy
+type schemaKey struct {
+	Type   reflect.Type
+	Naming NamingStrategy
+}
+
+var schemaInfos sync.Map
+
+// getSchemaInfo returns the schemaInfo of the given struct type, named with the given NamingStrategy
+func getSchemaInfo(schemaType reflect.Type, naming NamingStrategy) *schemaInfo {
+	key := schemaKey{schemaType, naming}
+	if info, found := schemaInfos.Load(key); found {
+		return info.(*schemaInfo)
+	}
+	info := &schemaInfo{
+		Type:    schemaType,
+		Table:   naming.TableName(schemaType.Name()),
+		Fields:  []schemaField{},
+		Columns: []string{},
+	}
+	if namer, ok := reflect.New(schemaType).Interface().(TableNamer); ok {
+		info.Table = namer.TableName()
+	}
+	for i := 0; i < schemaType.NumField(); i++ {
+		field := schemaType.Field(i)
+		options := getOptions(field)
+		if options.Ignore {
+			continue
+		}
+		column := naming.ColumnName(field.Name)
+		if len(options.ColumnName) > 0 {
+			column = options.ColumnName
+		}
+		if len(options.ForeignKey) > 0 {
+			column = column + "_" + naming.ColumnName(options.ForeignKey)
+		}
+		info.Fields = append(info.Fields, schemaField{StructField: field, Column: column, Options: options})
+		info.Columns = append(info.Columns, column)
+	}
+	info.Indexes = getIndexes(info.Table, info.Fields)
+	actual, _ := schemaInfos.LoadOrStore(key, info)
+	return actual.(*schemaInfo)
+}
+
+// Keys returns the fields that are part of the primary key
+func (info schemaInfo) Keys() []schemaField {
+	keys := []schemaField{}
+	for _, field := range info.Fields {
+		if field.Options.PrimaryKey {
+			keys = append(keys, field)
+		}
+	}
+	return keys
+}
+
+// Field returns the field stored in the given column
+func (info schemaInfo) Field(column string) (schemaField, bool) {
+	for _, field := range info.Fields {
+		if field.Column == column {
+			return field, true
+		}
+	}
+	return schemaField{}, false
+}
+
+// isGenerated tells if the database generates the value of the field when a row is inserted with the given value
+//
+// Autoincrement fields are generated when they are zero.
+// Fields with a default are generated when they are nil or a zero struct or array (e.g.: time.Time, uuid.UUID),
+// a zero number, string, or bool is a value like any other
+func (field schemaField) isGenerated(value reflect.Value) bool {
+	if !value.IsZero() {
+		return false
+	}
+	if field.Options.AutoIncrement {
+		return true
+	}
+	if len(field.Options.Default) == 0 {
+		return false
+	}
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map, reflect.Struct, reflect.Array:
+		return true
+	default:
+		return false
+	}
+}
+
+// getColumnValue returns the value to store in the column of a field
+//
+// For foreign keys, this is the value of the key in the foreign struct (nil if the foreign struct is nil)
+func getColumnValue(field reflect.StructField, options fieldOptions, value reflect.Value) (interface{}, error) {
+	if len(options.ForeignKey) == 0 {
+		return value.Interface(), nil
+	}
+	foreignType := field.Type
+	foreignValue := value
+	if foreignType.Kind() == reflect.Ptr {
+		foreignType = foreignType.Elem()
+		foreignValue = value.Elem()
+	}
+	if foreignType.Kind() != reflect.Struct {
+		return nil, errors.ArgumentInvalid.With("typeof", field.Name).WithStack()
+	}
+	for j := 0; j < foreignType.NumField(); j++ {
+		if foreignType.Field(j).Name == options.ForeignKey {
+			if !foreignValue.IsValid() {
+				return nil, nil
+			}
+			return foreignValue.Field(j).Interface(), nil
+		}
+	}
+	return nil, errors.ArgumentInvalid.With("foreignkey", options.ForeignKey).WithStack()
+}
+
+// sortSchemas sorts the schemas so that the schemas referenced by foreign keys come before the schemas that reference them
+//
+// Schemas that do not depend on each other keep their order, references to schemas that are not given are ignored.
+// If the foreign keys of the schemas form a cycle, an errors.ArgumentInvalid is returned
+func sortSchemas(schemas []interface{}, naming NamingStrategy) ([]interface{}, error) {
+	types := make([]reflect.Type, len(schemas))
+	for i, schema := range schemas {
+		types[i], _ = getTypeAndValue(schema)
+	}
+	sorted := make([]interface{}, 0, len(schemas))
+	done := make([]bool, len(schemas))
+	visiting := make([]bool, len(schemas))
+	var visit func(i int) error
+	visit = func(i int) error {
+		if done[i] {
+			return nil
+		}
+		if visiting[i] {
+			return errors.ArgumentInvalid.With("schemas", "cyclic foreign keys on "+types[i].Name()).WithStack()
+		}
+		visiting[i] = true
+		for _, field := range getSchemaInfo(types[i], naming).Fields {
+			if len(field.Options.ForeignKey) == 0 {
+				continue
+			}
+			foreignType := field.Type
+			if foreignType.Kind() == reflect.Ptr {
+				foreignType = foreignType.Elem()
+			}
+			for j, schemaType := range types {
+				if j != i && schemaType == foreignType {
+					if err := visit(j); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		visiting[i] = false
+		done[i] = true
+		sorted = append(sorted, schemas[i])
+		return nil
+	}
+	for i := range schemas {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
@@ -0,0 +1,167 @@
+package sql
+
+import (
+	"container/list"
+	"context"
+	gosql "database/sql"
+	"strings"
+	"sync"
+)
+
+// maxCachedStatements is the maximum number of prepared statements a DB keeps
+const maxCachedStatements = 256
+
+// statementCache runs the statements of a DB through prepared statements, keyed by their SQL text
+//
+// Only SELECT, INSERT, UPDATE, and DELETE statements are prepared, the others run directly on the DB.
+// When the cache is full, the least recently used statement is closed once it is not used anymore
+type statementCache struct {
+	sync.Mutex
+	db         *gosql.DB
+	statements map[string]*list.Element
+	recent     *list.List // of *cachedStatement, the most recently used first
+}
+
+// cachedStatement is a prepared statement of the cache
+type cachedStatement struct {
+	query     string
+	statement *gosql.Stmt
+	users     int  // the number of statements running with it
+	evicted   bool // the statement is closed when its last user releases it
+}
+
+func newStatementCache(db *gosql.DB) *statementCache {
+	return &statementCache{db: db, statements: map[string]*list.Element{}, recent: list.New()}
+}
+
+// prepare returns the prepared statement of the given query, nil if the query should run directly on the DB
+//
+// The statement is prepared outside of the lock so the other statements do not wait for the database.
+// The returned statement must be released once it ran
+func (cache *statementCache) prepare(ctx context.Context, query string) (*cachedStatement, error) {
+	verb := strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0])
+	if verb != "SELECT" && verb != "INSERT" && verb != "UPDATE" && verb != "DELETE" {
+		return nil, nil
+	}
+	if cached := cache.use(query); cached != nil {
+		return cached, nil
+	}
+	statement, err := cache.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	cache.Lock()
+	if element, found := cache.statements[query]; found {
+		// another caller prepared the same query meanwhile
+		cached := element.Value.(*cachedStatement)
+		cached.users++
+		cache.recent.MoveToFront(element)
+		cache.Unlock()
+		statement.Close()
+		return cached, nil
+	}
+	cached := &cachedStatement{query: query, statement: statement, users: 1}
+	cache.statements[query] = cache.recent.PushFront(cached)
+	evicted := []*gosql.Stmt{}
+	for cache.recent.Len() > maxCachedStatements {
+		oldest := cache.recent.Back()
+		cache.recent.Remove(oldest)
+		old := oldest.Value.(*cachedStatement)
+		delete(cache.statements, old.query)
+		old.evicted = true
+		if old.users == 0 {
+			evicted = append(evicted, old.statement)
+		}
+	}
+	cache.Unlock()
+	for _, statement := range evicted {
+		statement.Close()
+	}
+	return cached, nil
+}
+
+// use returns the cached statement of the given query, nil if the query was not prepared yet
+func (cache *statementCache) use(query string) *cachedStatement {
+	cache.Lock()
+	defer cache.Unlock()
+	if element, found := cache.statements[query]; found {
+		cached := element.Value.(*cachedStatement)
+		cached.users++
+		cache.recent.MoveToFront(element)
+		return cached
+	}
+	return nil
+}
+
+// release tells the cache a statement is not used anymore, it is closed if it was evicted
+//
+// Rows that were returned by the statement can still be read after its release
+func (cache *statementCache) release(cached *cachedStatement) {
+	cache.Lock()
+	cached.users--
+	closing := cached.evicted && cached.users == 0
+	cache.Unlock()
+	if closing {
+		cached.statement.Close()
+	}
+}
+
+// Len returns the number of prepared statements in the cache
+func (cache *statementCache) Len() int {
+	cache.Lock()
+	defer cache.Unlock()
+	return len(cache.statements)
+}
+
+// Close closes all the prepared statements of the cache
+func (cache *statementCache) Close() (err error) {
+	cache.Lock()
+	defer cache.Unlock()
+	for query, element := range cache.statements {
+		if closeErr := element.Value.(*cachedStatement).statement.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		delete(cache.statements, query)
+	}
+	cache.recent.Init()
+	return
+}
+
+// ExecContext executes a query without returning any rows through its prepared statement
+func (cache *statementCache) ExecContext(ctx context.Context, query string, args ...interface{}) (gosql.Result, error) {
+	statement, err := cache.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if statement == nil {
+		return cache.db.ExecContext(ctx, query, args...)
+	}
+	defer cache.release(statement)
+	return statement.statement.ExecContext(ctx, args...)
+}
+
+// QueryContext executes a query that returns rows through its prepared statement
+func (cache *statementCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*gosql.Rows, error) {
+	statement, err := cache.prepare(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	if statement == nil {
+		return cache.db.QueryContext(ctx, query, args...)
+	}
+	defer cache.release(statement)
+	return statement.statement.QueryContext(ctx, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row through its prepared statement
+//
+// If the query cannot be prepared, it runs directly on the DB so the error is reported by the Row's Scan method
+func (cache *statementCache) QueryRowContext(ctx context.Context, query string, args ...interface{}) *gosql.Row {
+	statement, err := cache.prepare(ctx, query)
+	if err != nil || statement == nil {
+		return cache.db.QueryRowContext(ctx, query, args...)
+	}
+	defer cache.release(statement)
+	return statement.statement.QueryRowContext(ctx, args...)
+}
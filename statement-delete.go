@@ -20,9 +20,10 @@ func (statement DeleteStatement) With(db *DB) Statement {
 
 // Build builds the statement to be executed by the DB
 func (statement DeleteStatement) Build(table string, columns []string, queries Queries) (string, []interface{}) {
-	where, parms := queries.WhereClause()
+	dialect := getDialect(statement.DB)
+	where, parms := queries.WhereClauseWith(dialect, []interface{}{})
 	if len(where) > 0 {
-		return fmt.Sprintf("DELETE FROM %s WHERE %s", table, where), parms
+		return fmt.Sprintf("DELETE FROM %s WHERE %s", dialect.Quote(table), where), parms
 	}
-	return fmt.Sprintf("DELETE FROM %s", table), parms
+	return fmt.Sprintf("DELETE FROM %s", dialect.Quote(table)), parms
 }
\ No newline at end of file
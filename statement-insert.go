@@ -21,14 +21,19 @@ func (statement InsertStatement) With(db *DB) Statement {
 
 // Build builds the statement to be executed by the DB
 func (statement InsertStatement) Build(table string, columns []string, queries Queries) (string, []interface{}) {
-	cols   := []string{}
-	values := []string{}
-	parms  := []interface{}{}
+	dialect := getDialect(statement.DB)
+	cols    := []string{}
+	values  := []string{}
+	parms   := []interface{}{}
 
-	for key, query := range queries {
-		cols   = append(cols, strings.TrimPrefix(key, "="))
+	for _, key := range queries.keys(columns) {
+		query := queries[key]
+		if strings.HasPrefix(key, "#") {
+			continue
+		}
+		cols   = append(cols, dialect.Quote(strings.TrimPrefix(key, "=")))
 		parms  = append(parms, query[1])
-		values = append(values, fmt.Sprintf("$%d", len(parms)))
+		values = append(values, dialect.Placeholder(len(parms)))
 	}
-	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", table, strings.Join(cols, ", "), strings.Join(values, ", ")), parms
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", dialect.Quote(table), strings.Join(cols, ", "), strings.Join(values, ", ")), parms
 }
\ No newline at end of file
@@ -21,9 +21,19 @@ func (statement SelectStatement) With(db *DB) Statement {
 
 // Build builds the statement to be executed by the DB
 func (statement SelectStatement) Build(table string, columns []string, queries Queries) (string, []interface{}) {
-	where, parms := queries.WhereClause()
+	dialect := getDialect(statement.DB)
+	where, parms := queries.WhereClauseWith(dialect, []interface{}{})
+	cols := make([]string, len(columns))
+	for i, column := range columns {
+		cols[i] = dialect.Quote(column)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ", "), dialect.Quote(table))
 	if len(where) > 0 {
-		return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columns, ", "), table, where), parms
+		query = query + " WHERE " + where
+	}
+	order, parms := queries.OrderClauseWith(dialect, parms)
+	if len(order) > 0 {
+		query = query + " " + order
 	}
-	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), table), parms
+	return query, parms
 }
\ No newline at end of file
@@ -21,17 +21,21 @@ func (statement UpdateStatement) With(db *DB) Statement {
 
 // Build builds the statement to be executed by the DB
 func (statement UpdateStatement) Build(table string, columns []string, queries Queries) (string, []interface{}) {
-	where, parms := queries.WhereClause()
+	dialect := getDialect(statement.DB)
 	assignments := []string{}
+	parms := []interface{}{}
 
-	if len(where) == 0 {
-		return "", []interface{}{}
-	}
-	for key, values := range queries {
+	// The assignments come first, so their parameters must come first for dialects with positional placeholders (?)
+	for _, key := range queries.keys(columns) {
+		values := queries[key]
 		if operator, ok := values[0].(QueryOperator); ok && operator.Operator == QuerySet.Operator {
 			parms = append(parms, values[1])
-			assignments = append(assignments, fmt.Sprintf("%s = $%d", strings.TrimPrefix(key,"="), len(parms)))
+			assignments = append(assignments, fmt.Sprintf("%s = %s", dialect.Quote(strings.TrimPrefix(key, "=")), dialect.Placeholder(len(parms))))
 		}
 	}
-	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(assignments, ", "), where), parms
+	where, parms := queries.WhereClauseWith(dialect, parms)
+	if len(where) == 0 || len(assignments) == 0 {
+		return "", []interface{}{}
+	}
+	return fmt.Sprintf("UPDATE %s SET %s WHERE %s", dialect.Quote(table), strings.Join(assignments, ", "), where), parms
 }
\ No newline at end of file
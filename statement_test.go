@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -94,6 +95,114 @@ func (suite *StatementSuite) TestCannotBuildUpdateWithEmptyQueries() {
 	suite.T().Logf("Statement: %s, parms: %#v", stmt, parms)
 }
 
+func (suite *StatementSuite) TestShouldBuildStableStatements() {
+	queries := sql.Queries{}.Add("name", "Doe").Add("age", sql.QueryGreater, 18).Add("id", "1", "2")
+	for i := 0; i < 10; i++ {
+		stmt, parms := sql.SelectStatement{}.Build("person", []string{"id", "name"}, queries)
+		suite.Require().Equal("SELECT id, name FROM person WHERE age > $1 AND id IN ($2, $3) AND name = $4", stmt)
+		suite.Require().Equal([]interface{}{18, "1", "2", "Doe"}, parms)
+	}
+
+	queries = sql.Queries{}.Add("name", sql.QuerySet, "Doe").Add("age", sql.QuerySet, 18).Add("id", sql.QuerySet, "1234")
+	for i := 0; i < 10; i++ {
+		stmt, parms := sql.InsertStatement{}.Build("person", []string{"id", "name", "age"}, queries)
+		suite.Require().Equal("INSERT INTO person (id, name, age) VALUES ($1, $2, $3)", stmt, "Insert columns should follow the given columns")
+		suite.Require().Equal([]interface{}{"1234", "Doe", 18}, parms)
+		stmt, _ = sql.InsertStatement{}.Build("person", nil, queries)
+		suite.Require().Equal("INSERT INTO person (age, id, name) VALUES ($1, $2, $3)", stmt, "Insert columns should be sorted without columns")
+	}
+
+	queries = sql.Queries{}.Add("id", "1234").Add("name", sql.QuerySet, "Doe").Add("age", sql.QuerySet, 18)
+	for i := 0; i < 10; i++ {
+		stmt, parms := sql.UpdateStatement{}.Build("person", []string{"id", "name", "age"}, queries)
+		suite.Require().Equal("UPDATE person SET name = $1, age = $2 WHERE id = $3", stmt)
+		suite.Require().Equal([]interface{}{"Doe", 18, "1234"}, parms)
+	}
+}
+
+func (suite *StatementSuite) TestShouldCachePreparedStatements() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	for i := 0; i < 3; i++ {
+		_, err = db.FindAll(Person{}, sql.Queries{}.Add("name", "Doe").Add("age", sql.QueryGreater, 18))
+		suite.Require().Nil(err)
+		suite.Require().Nil(db.Insert(Person{fmt.Sprintf("%d", i), "Doe", 18, nil}))
+	}
+	suite.Assert().Equal(2, Prepared(suite.T().Name()), "There should be one prepared statement for FindAll and one for Insert")
+	suite.Assert().Equal([]string{
+		"SELECT id, name, age FROM person WHERE age > $1 AND name = $2",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3)",
+		"SELECT id, name, age FROM person WHERE age > $1 AND name = $2",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3)",
+		"SELECT id, name, age FROM person WHERE age > $1 AND name = $2",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3)",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *StatementSuite) TestShouldPrepareOnceForAllPages() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	for page := 0; page < 5; page++ {
+		_, err = db.FindAll(Person{}, sql.Queries{}.Add("name", "Doe").OrderBy("age").Limit(10).Offset(page*10+1))
+		suite.Require().Nil(err)
+	}
+	suite.Assert().Equal(1, Prepared(suite.T().Name()), "The pages should share their prepared statement")
+}
+
+func (suite *StatementSuite) TestShouldEvictLeastRecentlyUsedStatements() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	for count := 2; count < 302; count++ {
+		_, err = db.FindAll(Person{}, sql.Queries{}.Add("name", "Doe"))
+		suite.Require().Nil(err)
+		_, err = db.FindAll(Person{}, sql.Queries{}.Add("id", personIDs(count)...))
+		suite.Require().Nil(err)
+	}
+	suite.Assert().Equal(301, Prepared(suite.T().Name()), "The recently used statement should stay prepared")
+	_, err = db.FindAll(Person{}, sql.Queries{}.Add("id", personIDs(2)...))
+	suite.Require().Nil(err)
+	suite.Assert().Equal(302, Prepared(suite.T().Name()), "The least recently used statement should have been evicted")
+}
+
+func (suite *StatementSuite) TestCanRunStatementsConcurrently() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*100)
+	for worker := 0; worker < 8; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				_, err := db.FindAll(Person{}, sql.Queries{}.Add("id", personIDs(1+(worker*37+i)%300)...))
+				errs <- err
+			}
+		}(worker)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		suite.Require().Nil(err)
+	}
+}
+
+// personIDs returns count ids, so queries with a different count are different statements
+func personIDs(count int) []interface{} {
+	ids := make([]interface{}, count)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("%d", i)
+	}
+	return ids
+}
+
 // Suite Tools
 
 func (suite *StatementSuite) SetupSuite() {
@@ -0,0 +1,147 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// InsertManyOptions tells how InsertMany inserts the rows
+type InsertManyOptions struct {
+	// BatchSize is the maximum number of rows per statement, it defaults to the maximum the Dialect accepts (see Dialect.MaxInsertRows)
+	BatchSize int
+
+	// Copy copies the rows in bulk if the Dialect can (see Dialect.CopyIn), otherwise the rows are inserted by batches
+	Copy bool
+}
+
+// InsertMany inserts the blobs of a slice in their SQL table
+//
+// The blobs are inserted by batches of rows, each batch is one INSERT statement. All the batches run in one transaction.
+// The generated fields of the blobs are not filled (see Insert)
+func (db *DB) InsertMany(blobs interface{}, options InsertManyOptions) error {
+	return db.InsertManyContext(context.Background(), blobs, options)
+}
+
+// InsertManyContext inserts the blobs of a slice in their SQL table
+//
+// If the context contains a transaction (see Tx.ToContext), the blobs are inserted in a nested transaction of it
+func (db *DB) InsertManyContext(ctx context.Context, blobs interface{}, options InsertManyOptions) error {
+	return db.InTransaction(ctx, func(tx *Tx) error {
+		return tx.session().insertMany(ctx, blobs, options)
+	})
+}
+
+// preparer prepares statements, like a transaction
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*gosql.Stmt, error)
+}
+
+// insertMany inserts the blobs of a slice in their SQL table
+//
+// Consecutive blobs that set the same columns are inserted in the same batch
+func (session *session) insertMany(ctx context.Context, blobs interface{}, options InsertManyOptions) error {
+	log := session.Logger.Child(nil, "insert_many")
+	values := reflect.ValueOf(blobs)
+	if values.Kind() != reflect.Slice && values.Kind() != reflect.Array {
+		return errors.ArgumentInvalid.With("blobs", fmt.Sprintf("%T", blobs)).WithStack()
+	}
+	if values.Len() == 0 {
+		return nil
+	}
+	if first := values.Index(0); first.Kind() == reflect.Ptr && first.IsNil() {
+		return errors.ArgumentMissing.With("blobs[0]").WithStack()
+	}
+	blobType, _ := getTypeAndValue(values.Index(0).Interface())
+	info := getSchemaInfo(blobType, session.Naming)
+	log = log.Record("table", info.Table)
+
+	columns := []string{}
+	rows := [][]interface{}{}
+	for i := 0; i < values.Len(); i++ {
+		if value := values.Index(i); value.Kind() == reflect.Ptr && value.IsNil() {
+			return errors.ArgumentMissing.With(fmt.Sprintf("blobs[%d]", i)).WithStack()
+		}
+		rowType, rowValue := getTypeAndValue(values.Index(i).Interface())
+		if rowType != blobType {
+			return errors.ArgumentInvalid.With("blobs", rowType.String()).WithStack()
+		}
+		queries, err := insertQueries(log, info, rowValue)
+		if err != nil {
+			return err
+		}
+		rowColumns, parms := insertColumns(info, queries)
+		if len(rows) > 0 && (strings.Join(rowColumns, ",") != strings.Join(columns, ",") || len(rows) >= session.batchSize(len(columns), options)) {
+			if err := session.insertRows(ctx, log, info.Table, columns, rows, options); err != nil {
+				return err
+			}
+			rows = rows[:0]
+		}
+		columns = rowColumns
+		rows = append(rows, parms)
+	}
+	return session.insertRows(ctx, log, info.Table, columns, rows, options)
+}
+
+// batchSize returns the maximum number of rows of the given number of columns to insert per statement
+func (session *session) batchSize(columns int, options InsertManyOptions) int {
+	size := session.Dialect.MaxInsertRows(columns)
+	if options.BatchSize > 0 && options.BatchSize < size {
+		size = options.BatchSize
+	}
+	return size
+}
+
+// insertRows inserts rows that set the same columns in one statement, or copies them if asked and the Dialect can
+func (session *session) insertRows(ctx context.Context, log *logger.Logger, table string, columns []string, rows [][]interface{}, options InsertManyOptions) error {
+	if options.Copy {
+		if preparer, ok := session.exec.(preparer); ok {
+			if statement := session.Dialect.CopyIn(table, columns); len(statement) > 0 {
+				return copyRows(ctx, log, preparer, statement, rows)
+			}
+		}
+	}
+	statement := insertRows(session.Dialect, table, columns, len(rows))
+	parms := make([]interface{}, 0, len(rows)*len(columns))
+	for _, row := range rows {
+		parms = append(parms, row...)
+	}
+	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
+	_, err := session.exec.ExecContext(ctx, statement, parms...)
+	return err
+}
+
+// copyRows copies rows with the bulk copy statement of a Dialect
+func copyRows(ctx context.Context, log *logger.Logger, preparer preparer, statement string, rows [][]interface{}) error {
+	log.Tracef("Statement: %s with %d rows", statement, len(rows))
+	stmt, err := preparer.PrepareContext(ctx, statement)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, row := range rows {
+		if _, err := stmt.ExecContext(ctx, row...); err != nil {
+			return err
+		}
+	}
+	_, err = stmt.ExecContext(ctx)
+	return err
+}
+
+// insertRows returns the INSERT INTO ... VALUES statement of the given number of rows, its parameters are the values of the columns, row after row
+func insertRows(dialect Dialect, table string, columns []string, count int) string {
+	rows := make([]string, count)
+	values := make([]string, len(columns))
+	for row := range rows {
+		for i := range columns {
+			values[i] = dialect.Placeholder(row*len(columns) + i + 1)
+		}
+		rows[row] = "(" + strings.Join(values, ", ") + ")"
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", dialect.Quote(table), quoteColumns(dialect, columns, ""), strings.Join(rows, ", "))
+}
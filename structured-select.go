@@ -0,0 +1,83 @@
+package sql
+
+import (
+	"context"
+	"reflect"
+
+	"github.com/gildas/go-errors"
+)
+
+// Select retrieves all objects that satisfy the queries into destination
+//
+// destination must be a pointer to a slice of structs or of pointers to structs (e.g.: *[]Person or *[]*Person),
+// the schema is the type of the slice items.
+func (db *DB) Select(destination interface{}, queries Queries) error {
+	return db.SelectContext(context.Background(), destination, queries)
+}
+
+// SelectContext retrieves all objects that satisfy the queries into destination
+//
+// If the context contains a transaction (see Tx.ToContext), the objects are retrieved within it
+func (db *DB) SelectContext(ctx context.Context, destination interface{}, queries Queries) error {
+	return db.session(ctx).selectAll(ctx, destination, queries)
+}
+
+// Get retrieves the first object that satisfies the queries into destination
+//
+// destination must be a pointer to a struct, the schema is the type of that struct.
+// If no object satisfies the queries, an errors.NotFound is returned
+func (db *DB) Get(destination interface{}, queries Queries) error {
+	return db.GetContext(context.Background(), destination, queries)
+}
+
+// GetContext retrieves the first object that satisfies the queries into destination
+//
+// If the context contains a transaction (see Tx.ToContext), the object is retrieved within it
+func (db *DB) GetContext(ctx context.Context, destination interface{}, queries Queries) error {
+	return db.session(ctx).get(ctx, destination, queries)
+}
+
+// selectAll retrieves all objects that satisfy the queries into destination
+func (session *session) selectAll(ctx context.Context, destination interface{}, queries Queries) error {
+	destinationValue := reflect.ValueOf(destination)
+	if destinationValue.Kind() != reflect.Ptr || destinationValue.IsNil() || destinationValue.Elem().Kind() != reflect.Slice {
+		return errors.ArgumentInvalid.With("destination", reflect.TypeOf(destination)).WithStack()
+	}
+	sliceValue := destinationValue.Elem()
+	itemType := sliceValue.Type().Elem()
+	schemaType := itemType
+	if schemaType.Kind() == reflect.Ptr {
+		schemaType = schemaType.Elem()
+	}
+	if schemaType.Kind() != reflect.Struct {
+		return errors.ArgumentInvalid.With("destination", reflect.TypeOf(destination)).WithStack()
+	}
+	blobs, err := session.findAll(ctx, reflect.New(schemaType).Interface(), queries)
+	if err != nil {
+		return err
+	}
+	results := reflect.MakeSlice(sliceValue.Type(), 0, len(blobs))
+	for _, blob := range blobs {
+		if itemType.Kind() == reflect.Ptr {
+			results = reflect.Append(results, reflect.ValueOf(blob))
+		} else {
+			results = reflect.Append(results, reflect.ValueOf(blob).Elem())
+		}
+	}
+	sliceValue.Set(results)
+	return nil
+}
+
+// get retrieves the first object that satisfies the queries into destination
+func (session *session) get(ctx context.Context, destination interface{}, queries Queries) error {
+	destinationValue := reflect.ValueOf(destination)
+	if destinationValue.Kind() != reflect.Ptr || destinationValue.IsNil() || destinationValue.Elem().Kind() != reflect.Struct {
+		return errors.ArgumentInvalid.With("destination", reflect.TypeOf(destination)).WithStack()
+	}
+	blob, err := session.find(ctx, destination, queries)
+	if err != nil {
+		return err
+	}
+	destinationValue.Elem().Set(reflect.ValueOf(blob).Elem())
+	return nil
+}
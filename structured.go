@@ -1,190 +1,568 @@
 package sql
 
 import (
+	"context"
+	gosql "database/sql"
 	"fmt"
 	"reflect"
 	"strings"
 	"time"
 
 	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
 )
 
-// CreateTable creates an SQL Table from a schema
-func (db *DB) CreateTable(schema interface{}) error {
-	log := db.Logger.Child(nil, "create")
+// CreateTable creates the SQL Tables of the given schemas
+//
+// The tables are created in the order of their foreign keys, referenced tables first
+func (db *DB) CreateTable(schemas ...interface{}) error {
+	return db.CreateTableContext(context.Background(), schemas...)
+}
+
+// CreateTableContext creates the SQL Tables of the given schemas
+//
+// If the context contains a transaction (see Tx.ToContext), the tables are created within it
+func (db *DB) CreateTableContext(ctx context.Context, schemas ...interface{}) error {
+	return db.session(ctx).createTables(ctx, schemas)
+}
+
+// DeleteTable deletes (drops) the SQL tables that represent the given schemas
+//
+// The tables are deleted in the reverse order of their foreign keys, referencing tables first
+func (db *DB) DeleteTable(schemas ...interface{}) error {
+	return db.DeleteTableContext(context.Background(), schemas...)
+}
+
+// DeleteTableContext deletes (drops) the SQL tables that represent the given schemas
+//
+// If the context contains a transaction (see Tx.ToContext), the tables are deleted within it
+func (db *DB) DeleteTableContext(ctx context.Context, schemas ...interface{}) error {
+	return db.session(ctx).deleteTables(ctx, schemas)
+}
+
+// Insert insert a blob in its SQL table
+//
+// If the blob is a pointer, its fields generated by the database (autoincrement keys, defaults) are filled with the inserted values
+func (db *DB) Insert(blob interface{}) error {
+	return db.InsertContext(context.Background(), blob)
+}
+
+// InsertContext insert a blob in its SQL table
+//
+// If the context contains a transaction (see Tx.ToContext), the blob is inserted within it
+func (db *DB) InsertContext(ctx context.Context, blob interface{}) error {
+	return db.session(ctx).insert(ctx, blob)
+}
+
+// FindAll retrieves all objects of a schema that satisfy the queries
+func (db *DB) FindAll(schema interface{}, queries Queries) ([]interface{}, error) {
+	return db.FindAllContext(context.Background(), schema, queries)
+}
+
+// FindAllContext retrieves all objects of a schema that satisfy the queries
+//
+// If the context contains a transaction (see Tx.ToContext), the objects are retrieved within it
+func (db *DB) FindAllContext(ctx context.Context, schema interface{}, queries Queries) ([]interface{}, error) {
+	return db.session(ctx).findAll(ctx, schema, queries)
+}
+
+// Find retrieves the first object of a schema that satisfies the queries
+func (db *DB) Find(schema interface{}, queries Queries) (interface{}, error) {
+	return db.FindContext(context.Background(), schema, queries)
+}
+
+// FindContext retrieves the first object of a schema that satisfies the queries
+//
+// If the context contains a transaction (see Tx.ToContext), the object is retrieved within it
+func (db *DB) FindContext(ctx context.Context, schema interface{}, queries Queries) (interface{}, error) {
+	return db.session(ctx).find(ctx, schema, queries)
+}
+
+// AffectOptions are the options of UpdateAllAffected and DeleteAllAffected
+type AffectOptions struct {
+	// MustMatch tells to return an errors.NotFound when no row satisfied the queries
+	MustMatch bool
+}
+
+// AffectedResult is the result of UpdateAllAffected and DeleteAllAffected
+type AffectedResult struct {
+	// RowsAffected is the number of rows that were updated or deleted, -1 if the driver cannot count them
+	//
+	// MySQL counts only the rows that were changed, unless the connection sets CLIENT_FOUND_ROWS
+	RowsAffected int64
+}
+
+// UpdateAll updates all objects of a schema that satisfy the queries
+func (db *DB) UpdateAll(schema interface{}, queries Queries) error {
+	return db.UpdateAllContext(context.Background(), schema, queries)
+}
+
+// UpdateAllContext updates all objects of a schema that satisfy the queries
+//
+// If the context contains a transaction (see Tx.ToContext), the objects are updated within it
+func (db *DB) UpdateAllContext(ctx context.Context, schema interface{}, queries Queries) error {
+	_, err := db.session(ctx).updateAll(ctx, schema, queries)
+	return err
+}
+
+// UpdateAllAffected updates all objects of a schema that satisfy the queries and tells how many rows were updated
+//
+// If the options say the queries must match and no object satisfied them, an errors.NotFound is returned
+func (db *DB) UpdateAllAffected(schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	return db.UpdateAllAffectedContext(context.Background(), schema, queries, options)
+}
+
+// UpdateAllAffectedContext updates all objects of a schema that satisfy the queries and tells how many rows were updated
+//
+// If the context contains a transaction (see Tx.ToContext), the objects are updated within it
+func (db *DB) UpdateAllAffectedContext(ctx context.Context, schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	session := db.session(ctx)
+	result, err := session.updateAll(ctx, schema, queries)
+	return session.affected(ctx, schema, queries, options, result, err, true)
+}
+
+// Update updates the row of a blob in its SQL table
+//
+// The row is matched on the fields tagged as key, all the other columns are updated.
+// If no row matched, an errors.NotFound is returned
+func (db *DB) Update(blob interface{}) error {
+	return db.UpdateContext(context.Background(), blob)
+}
+
+// UpdateContext updates the row of a blob in its SQL table
+//
+// If the context contains a transaction (see Tx.ToContext), the row is updated within it
+func (db *DB) UpdateContext(ctx context.Context, blob interface{}) error {
+	return db.session(ctx).update(ctx, blob)
+}
+
+// DeleteAll deletes all objects of a schema that satisfy the queries
+func (db *DB) DeleteAll(schema interface{}, queries Queries) error {
+	return db.DeleteAllContext(context.Background(), schema, queries)
+}
+
+// DeleteAllContext deletes all objects of a schema that satisfy the queries
+//
+// If the context contains a transaction (see Tx.ToContext), the objects are deleted within it
+func (db *DB) DeleteAllContext(ctx context.Context, schema interface{}, queries Queries) error {
+	_, err := db.session(ctx).deleteAll(ctx, schema, queries)
+	return err
+}
+
+// DeleteAllAffected deletes all objects of a schema that satisfy the queries and tells how many rows were deleted
+//
+// If the options say the queries must match and no object satisfied them, an errors.NotFound is returned
+func (db *DB) DeleteAllAffected(schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	return db.DeleteAllAffectedContext(context.Background(), schema, queries, options)
+}
+
+// DeleteAllAffectedContext deletes all objects of a schema that satisfy the queries and tells how many rows were deleted
+//
+// If the context contains a transaction (see Tx.ToContext), the objects are deleted within it
+func (db *DB) DeleteAllAffectedContext(ctx context.Context, schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	session := db.session(ctx)
+	result, err := session.deleteAll(ctx, schema, queries)
+	return session.affected(ctx, schema, queries, options, result, err, false)
+}
+
+// Delete deletes the row of a blob from its SQL table
+//
+// The row is matched on the fields tagged as key.
+// If no row matched, an errors.NotFound is returned
+func (db *DB) Delete(blob interface{}) error {
+	return db.DeleteContext(context.Background(), blob)
+}
+
+// DeleteContext deletes the row of a blob from its SQL table
+//
+// If the context contains a transaction (see Tx.ToContext), the row is deleted within it
+func (db *DB) DeleteContext(ctx context.Context, blob interface{}) error {
+	return db.session(ctx).delete(ctx, blob)
+}
+
+// executor is what the structured operations need to run statements, it is implemented by *gosql.DB, *gosql.Tx, and *statementCache
+type executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (gosql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*gosql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *gosql.Row
+}
+
+// session runs the structured operations of a DB, either directly or within a transaction
+type session struct {
+	*DB
+	exec executor
+}
+
+// session returns a session that runs within the transaction stored in the context, or directly on the DB
+func (db *DB) session(ctx context.Context) *session {
+	if tx, err := TxFromContext(ctx); err == nil {
+		return tx.session()
+	}
+	if db.statements != nil {
+		return &session{DB: db, exec: db.statements}
+	}
+	return &session{DB: db, exec: db.db}
+}
+
+// createTables creates the SQL Tables of the given schemas, referenced tables first
+func (session *session) createTables(ctx context.Context, schemas []interface{}) error {
+	sorted, err := sortSchemas(schemas, session.Naming)
+	if err != nil {
+		return err
+	}
+	for _, schema := range sorted {
+		if err := session.createTable(ctx, schema); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteTables deletes (drops) the SQL tables of the given schemas, referencing tables first
+func (session *session) deleteTables(ctx context.Context, schemas []interface{}) error {
+	sorted, err := sortSchemas(schemas, session.Naming)
+	if err != nil {
+		return err
+	}
+	for i := len(sorted) - 1; i >= 0; i-- {
+		if err := session.deleteTable(ctx, sorted[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTable creates an SQL Table from a schema
+func (session *session) createTable(ctx context.Context, schema interface{}) error {
+	log := session.Logger.Child(nil, "create")
 	schemaType, _ := getTypeAndValue(schema)
-	table := strings.ToLower(schemaType.Name())
+	info := getSchemaInfo(schemaType, session.Naming)
+	table := info.Table
 
 	log = log.Record("table", table)
 	log.Tracef("Schema %s => table=%s", schemaType.Name(), table)
+	keys := info.Keys()
+	compositeKey := len(keys) > 1
 	columns := []string{}
-	for i := 0; i < schemaType.NumField(); i++ {
-		field := schemaType.Field(i)
-		options := getOptions(field)
-		if getOptions(field).Ignore {
-			continue
+	constraints := []string{}
+	if compositeKey {
+		keyColumns := make([]string, len(keys))
+		for i, key := range keys {
+			keyColumns[i] = session.Dialect.Quote(key.Column)
 		}
-		log.Tracef("Field: %s, type=%s, kind=%s", field.Name, field.Type.Name(), field.Type.Kind())
-		column := strings.Builder{}
-		if len(options.ColumnName) > 0 {
-			column.WriteString(options.ColumnName)
-		} else {
-			column.WriteString(strings.ToLower(field.Name))
+		constraints = append(constraints, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(keyColumns, ", ")))
+	}
+	for _, field := range info.Fields {
+		column, _, constraint, err := session.columnDefinition(log, field, compositeKey)
+		if err != nil {
+			return err
 		}
-		if len(options.ForeignKey) > 0 {
-			column.WriteString("_")
-			column.WriteString(strings.ToLower(options.ForeignKey))
+		columns = append(columns, column)
+		if len(constraint) > 0 {
+			constraints = append(constraints, constraint)
 		}
-		column.WriteString(" ")
-		if len(options.ForeignKey) > 0 {
-			log.Debugf("Field should use a foreign key: %s", options.ForeignKey)
-			foreignType := field.Type
-			if foreignType.Kind() == reflect.Ptr {
-				foreignType = foreignType.Elem()
-			}
-			if foreignType.Kind() != reflect.Struct {
-				return errors.ArgumentInvalid.With("typeof", field.Name).WithStack()
-			}
-			var sqltype string
-			for j := 0; j < foreignType.NumField(); j++ {
-				subfield := foreignType.Field(j)
-				if subfield.Name == options.ForeignKey {
-					log.Debugf("SubField: %s, type=%s, kind=%s", subfield.Name, subfield.Type.Name(), subfield.Type.Kind())
-					if len(options.ColumnType) > 0 {
-						sqltype = strings.ToUpper(options.ColumnType)
-					} else {
-						switch subfield.Type.Kind() {
-						case reflect.Array, reflect.Slice:
-							switch subfield.Type.Name() {
-							case "UUID":
-								sqltype = "UUID"
-							default:
-								return errors.ArgumentInvalid.With("typeof", subfield.Name).WithStack()
-							}
-						case reflect.String:
-							sqltype = "VARCHAR(80)"
-						case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-							sqltype = "INT"
-						default:
-							log.Errorf("Unsupported Kind: %s", subfield.Type.Kind())
-							return errors.ArgumentInvalid.With("typeof", subfield.Name).WithStack()
+	}
+	columns = append(columns, constraints...)
+	statement := fmt.Sprintf("CREATE TABLE %s (%s)", session.Dialect.Quote(table), strings.Join(columns, ", "))
+	parms := []interface{}{}
+	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
+	if _, err := session.exec.ExecContext(ctx, statement, parms...); err != nil {
+		return err
+	}
+	return session.createIndexes(ctx, table, info.Indexes)
+}
+
+// columnDefinition builds the definition of the column of a field for a CREATE TABLE statement
+//
+// It also returns the SQL type of the column, and its FOREIGN KEY constraint if the field is a foreign key
+func (session *session) columnDefinition(log *logger.Logger, field schemaField, compositeKey bool) (definition, sqltype, constraint string, err error) {
+	options := field.Options
+	log.Tracef("Field: %s, type=%s, kind=%s", field.Name, field.Type.Name(), field.Type.Kind())
+	column := strings.Builder{}
+	name := field.Column
+	column.WriteString(session.Dialect.Quote(name))
+	column.WriteString(" ")
+	if len(options.ForeignKey) > 0 {
+		log.Debugf("Field should use a foreign key: %s", options.ForeignKey)
+		foreignType := field.Type
+		if foreignType.Kind() == reflect.Ptr {
+			foreignType = foreignType.Elem()
+		}
+		if foreignType.Kind() != reflect.Struct {
+			return "", "", "", errors.ArgumentInvalid.With("typeof", field.Name).WithStack()
+		}
+		for j := 0; j < foreignType.NumField(); j++ {
+			subfield := foreignType.Field(j)
+			if subfield.Name == options.ForeignKey {
+				log.Debugf("SubField: %s, type=%s, kind=%s", subfield.Name, subfield.Type.Name(), subfield.Type.Kind())
+				if len(options.ColumnType) > 0 {
+					sqltype = strings.ToUpper(options.ColumnType)
+				} else {
+					switch subfield.Type.Kind() {
+					case reflect.Array, reflect.Slice, reflect.String, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+						if sqltype, err = session.Dialect.SQLType(subfield.Name, subfield.Type); err != nil {
+							return "", "", "", err
 						}
+					default:
+						log.Errorf("Unsupported Kind: %s", subfield.Type.Kind())
+						return "", "", "", errors.ArgumentInvalid.With("typeof", subfield.Name).WithStack()
 					}
-					log.Debugf("Matched? with %s", sqltype)
-					break
 				}
+				log.Debugf("Matched? with %s", sqltype)
+				break
 			}
-			log.Debugf("Foreign Type: %s, kind=%s => %s", foreignType.Name(), foreignType.Kind(), sqltype)
-			if len(sqltype) == 0 {
-				return errors.ArgumentInvalid.With("foreignkey", options.ForeignKey).WithStack()
-			}
-			column.WriteString(sqltype)
-		} else if len(options.ColumnType) > 0 {
-			column.WriteString(strings.ToUpper(options.ColumnType))
-		} else {
-			sqltype, err := getSQLType(field.Name, field.Type)
-			if err != nil {
-				log.Warnf("Field details: %#v", field)
-				log.Errorf("Unsupported Field Type %s (%s) for %s", field.Type.Name(), field.Type.Kind(), field.Name)
-				return err
-			}
-			column.WriteString(sqltype)
 		}
-		if options.PrimaryKey {
-			column.WriteString(" ")
-			column.WriteString("PRIMARY KEY")
+		log.Debugf("Foreign Type: %s, kind=%s => %s", foreignType.Name(), foreignType.Kind(), sqltype)
+		if len(sqltype) == 0 {
+			return "", "", "", errors.ArgumentInvalid.With("foreignkey", options.ForeignKey).WithStack()
+		}
+		if constraint, err = foreignKeyConstraint(session.Dialect, name, getSchemaInfo(foreignType, session.Naming), options); err != nil {
+			return "", "", "", err
+		}
+	} else if len(options.ColumnType) > 0 {
+		sqltype = strings.ToUpper(options.ColumnType)
+	} else {
+		if sqltype, err = session.Dialect.SQLType(field.Name, field.Type); err != nil {
+			log.Warnf("Field details: %#v", field)
+			log.Errorf("Unsupported Field Type %s (%s) for %s", field.Type.Name(), field.Type.Kind(), field.Name)
+			return "", "", "", err
 		}
-		columns = append(columns, column.String())
-		// TODO: How do we handle indices?
 	}
-	statement := fmt.Sprintf("CREATE TABLE %s (%s)", table, strings.Join(columns, ", "))
-	parms := []interface{}{}
-	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
-	_, err := db.db.Exec(statement, parms...)
-	return err
+	column.WriteString(sqltype)
+	if !options.Nullable && !options.PrimaryKey {
+		column.WriteString(" NOT NULL")
+	}
+	if len(options.Default) > 0 {
+		column.WriteString(" DEFAULT ")
+		column.WriteString(options.Default)
+	}
+	if options.PrimaryKey && !compositeKey {
+		column.WriteString(" ")
+		column.WriteString("PRIMARY KEY")
+	}
+	if options.AutoIncrement {
+		autoincrement, err := session.Dialect.AutoIncrement(options.PrimaryKey && !compositeKey)
+		if err != nil {
+			return "", "", "", err
+		}
+		column.WriteString(" ")
+		column.WriteString(autoincrement)
+	}
+	if len(options.Check) > 0 {
+		column.WriteString(" CHECK (")
+		column.WriteString(options.Check)
+		column.WriteString(")")
+	}
+	return column.String(), sqltype, constraint, nil
+}
+
+// createIndexes creates the indexes of a table
+//
+// All indexes are attempted, the error of each index that could not be created is reported
+func (session *session) createIndexes(ctx context.Context, table string, indexes []Index) error {
+	log := session.Logger.Child(nil, "create_index").Record("table", table)
+	errs := errors.MultiError{}
+	for _, index := range indexes {
+		statement, err := session.Dialect.CreateIndex(table, index)
+		if err != nil {
+			errs.Append(err)
+			continue
+		}
+		log.Tracef("Statement: %s", statement)
+		if _, err := session.exec.ExecContext(ctx, statement); err != nil {
+			log.Errorf("Failed to create index %s: %v", index.Name, err)
+			errs.Append(errors.CreationFailed.With("index", index.Name).Wrap(err))
+		}
+	}
+	return errs.AsError()
+}
+
+// foreignKeyActions are the actions of the ondelete and onupdate tag options
+var foreignKeyActions = map[string]string{
+	"cascade":    "CASCADE",
+	"restrict":   "RESTRICT",
+	"setnull":    "SET NULL",
+	"setdefault": "SET DEFAULT",
+	"noaction":   "NO ACTION",
+}
+
+// foreignKeyConstraint builds the FOREIGN KEY constraint of a column that references a foreign schema
+func foreignKeyConstraint(dialect Dialect, column string, foreignInfo *schemaInfo, options fieldOptions) (string, error) {
+	foreignColumn := strings.ToLower(options.ForeignKey)
+	for _, field := range foreignInfo.Fields {
+		if field.Name == options.ForeignKey {
+			foreignColumn = field.Column
+			break
+		}
+	}
+	constraint := fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", dialect.Quote(column), dialect.Quote(foreignInfo.Table), dialect.Quote(foreignColumn))
+	if len(options.OnDelete) > 0 {
+		action, found := foreignKeyActions[options.OnDelete]
+		if !found {
+			return "", errors.ArgumentInvalid.With("ondelete", options.OnDelete).WithStack()
+		}
+		constraint = constraint + " ON DELETE " + action
+	}
+	if len(options.OnUpdate) > 0 {
+		action, found := foreignKeyActions[options.OnUpdate]
+		if !found {
+			return "", errors.ArgumentInvalid.With("onupdate", options.OnUpdate).WithStack()
+		}
+		constraint = constraint + " ON UPDATE " + action
+	}
+	return constraint, nil
 }
 
-// DeleteTable deletes (drops) the SQL table that represents the schema
-func (db *DB) DeleteTable(schema interface{}) error {
-	log := db.Logger.Child(nil, "drop")
+// deleteTable deletes (drops) the SQL table that represents the schema
+func (session *session) deleteTable(ctx context.Context, schema interface{}) error {
+	log := session.Logger.Child(nil, "drop")
 	schemaType, _ := getTypeAndValue(schema)
-	table := strings.ToLower(schemaType.Name())
+	table := getSchemaInfo(schemaType, session.Naming).Table
 
 	log = log.Record("table", table)
 	log.Tracef("Schema %s => table=%s", schemaType.Name(), table)
-	statement := fmt.Sprintf("DROP TABLE %s", table)
+	statement := fmt.Sprintf("DROP TABLE %s", session.Dialect.Quote(table))
 	log.Tracef("Statement: %s", statement)
-	_, err := db.db.Exec(statement)
+	_, err := session.exec.ExecContext(ctx, statement)
 	return err
 }
 
-// Insert insert a blob in its SQL table
-func (db *DB) Insert(blob interface{}) error {
-	log := db.Logger.Child(nil, "insert")
+// insert inserts a blob in its SQL table
+func (session *session) insert(ctx context.Context, blob interface{}) error {
+	log := session.Logger.Child(nil, "insert")
 	blobType, blobValue := getTypeAndValue(blob)
-	table := strings.ToLower(blobType.Name())
-	queries := Queries{}
+	info := getSchemaInfo(blobType, session.Naming)
+	table := info.Table
 
 	log = log.Record("table", table)
 	log.Tracef("Schema %s => table=%s", blobType.Name(), table)
-	for i := 0; i < blobType.NumField(); i++ {
-		field := blobType.Field(i)
-		options := getOptions(field)
-		if options.Ignore {
+	queries, err := insertQueries(log, info, blobValue)
+	if err != nil {
+		return err
+	}
+	generated := generatedFields(info, blobValue)
+	if reflect.TypeOf(blob).Kind() != reflect.Ptr || len(generated) == 0 {
+		statement, parms := InsertStatement{}.With(session.DB).Build(table, info.Columns, queries)
+		log.Tracef("Statement: %s with %d parameters", statement, len(parms))
+		_, err = session.exec.ExecContext(ctx, statement, parms...)
+		return err
+	}
+	return session.insertReturning(ctx, log, info, blobValue, queries, generated)
+}
+
+// insertReturning inserts the columns of a blob and fills its generated fields with the values of the inserted row
+//
+// If the Dialect cannot return the inserted row, the generated key is read with LastInsertId
+func (session *session) insertReturning(ctx context.Context, log *logger.Logger, info *schemaInfo, blobValue reflect.Value, queries Queries, generated []schemaField) error {
+	columns, parms := insertColumns(info, queries)
+	returning := make([]string, len(generated))
+	for i, field := range generated {
+		returning[i] = field.Column
+	}
+	statement := session.Dialect.InsertReturning(info.Table, columns, returning)
+	if len(statement) == 0 {
+		statement, parms = InsertStatement{}.With(session.DB).Build(info.Table, info.Columns, queries)
+		log.Tracef("Statement: %s with %d parameters", statement, len(parms))
+		result, err := session.exec.ExecContext(ctx, statement, parms...)
+		if err != nil {
+			return err
+		}
+		return setLastInsertID(log, result, generated, blobValue)
+	}
+	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
+	targets := make([]interface{}, len(generated))
+	for i, field := range generated {
+		target, err := getInterface(field.Name, field.Type, blobValue.Field(field.Index[0]))
+		if err != nil {
+			return err
+		}
+		targets[i] = target
+	}
+	return session.exec.QueryRowContext(ctx, statement, parms...).Scan(targets...)
+}
+
+// setLastInsertID sets the autoincrement field of a blob to the id generated by the database
+//
+// The other generated fields are left unchanged, the database does not return them
+func setLastInsertID(log *logger.Logger, result gosql.Result, generated []schemaField, blobValue reflect.Value) error {
+	for _, field := range generated {
+		if !field.Options.AutoIncrement {
 			continue
 		}
+		id, err := result.LastInsertId()
+		if err != nil {
+			return err
+		}
+		value := blobValue.Field(field.Index[0])
+		switch value.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value.SetInt(id)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value.SetUint(uint64(id))
+		default:
+			return errors.ArgumentInvalid.With("typeof", field.Name).WithStack()
+		}
+		log.Debugf("Generated %s: %d", field.Column, id)
+		return nil
+	}
+	return nil
+}
+
+// insertQueries builds the Queries that set the columns of a blob to insert
+//
+// The generated fields are left out, the database generates their value (see generatedFields)
+func insertQueries(log *logger.Logger, info *schemaInfo, blobValue reflect.Value) (Queries, error) {
+	queries := Queries{}
+	for _, field := range info.Fields {
 		log.Tracef("Field: %s, type=%s, kind=%s", field.Name, field.Type.Name(), field.Type.Kind())
-		value := blobValue.Field(i)
-		column := strings.ToLower(field.Name)
-		if len(options.ColumnName) > 0 {
-			column = options.ColumnName
-		}
-		if len(options.ForeignKey) > 0 {
-			column = column + "_" + strings.ToLower(options.ForeignKey)
-			foreignType := field.Type
-			foreignValue := value
-			log.Debugf("Foreign Value: %#v", foreignValue)
-			if foreignType.Kind() == reflect.Ptr {
-				foreignType = foreignType.Elem()
-				foreignValue = value.Elem()
-			}
-			if foreignType.Kind() != reflect.Struct {
-				return errors.ArgumentInvalid.With("typeof", field.Name).WithStack()
-			}
-			found := false
-			for j := 0; j < foreignType.NumField(); j++ {
-				subfield := foreignType.Field(j)
-				if subfield.Name == options.ForeignKey {
-					log.Tracef("SubField: %s, type=%s, kind=%s", subfield.Name, subfield.Type.Name(), subfield.Type.Kind())
-					found = true
-					value = foreignValue.Field(j)
-					break
-				}
-			}
-			if !found {
-				return errors.ArgumentInvalid.With("foreignkey", options.ForeignKey).WithStack()
-			}
+		if field.isGenerated(blobValue.Field(field.Index[0])) {
+			continue // the database generates the value
+		}
+		value, err := getColumnValue(field.StructField, field.Options, blobValue.Field(field.Index[0]))
+		if err != nil {
+			return queries, err
 		}
 		log.Debugf("Adding value: %#v", value)
-		queries.Add(column, QuerySet, value.Interface())
+		queries.Add(field.Column, QuerySet, value)
 	}
-	statement, parms := InsertStatement{}.With(db).Build(table, nil, queries)
-	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
-	_, err := db.db.Exec(statement, parms...)
-	return err
+	return queries, nil
 }
 
-// FindAll retrieves all objects of a schema that satisfy the queries
-func (db *DB) FindAll(schema interface{}, queries Queries) ([]interface{}, error) {
-	log := db.Logger.Child(nil, "find_all")
+// insertColumns returns the columns and the values set by the Queries of a blob to insert, in the order of the columns of the schema
+func insertColumns(info *schemaInfo, queries Queries) (columns []string, parms []interface{}) {
+	for _, key := range queries.keys(info.Columns) {
+		columns = append(columns, strings.TrimPrefix(key, "="))
+		parms = append(parms, queries[key][1])
+	}
+	return columns, parms
+}
+
+// generatedFields returns the fields of a blob whose value is generated by the database when it is inserted
+func generatedFields(info *schemaInfo, blobValue reflect.Value) []schemaField {
+	generated := []schemaField{}
+	for _, field := range info.Fields {
+		if field.isGenerated(blobValue.Field(field.Index[0])) {
+			generated = append(generated, field)
+		}
+	}
+	return generated
+}
+
+// findAll retrieves all objects of a schema that satisfy the queries
+func (session *session) findAll(ctx context.Context, schema interface{}, queries Queries) ([]interface{}, error) {
+	log := session.Logger.Child(nil, "find_all")
 	schemaType, _ := getTypeAndValue(schema)
-	table := strings.ToLower(schemaType.Name())
+	info := getSchemaInfo(schemaType, session.Naming)
+	table := info.Table
 
 	log = log.Record("table", table)
 	log.Tracef("Schema %s => table=%s", schemaType.Name(), table)
-	statement, parms := SelectStatement{}.With(db).Build(table, getColumns(schemaType), queries)
+	if err := queries.checkPaging(); err != nil {
+		return []interface{}{}, err
+	}
+	statement, parms := SelectStatement{}.With(session.DB).Build(table, info.Columns, queries)
 	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
-	rows, err := db.db.Query(statement, parms...)
+	rows, err := session.exec.QueryContext(ctx, statement, parms...)
 	if err != nil {
 		return []interface{}{}, err
 	}
@@ -194,17 +572,13 @@ func (db *DB) FindAll(schema interface{}, queries Queries) ([]interface{}, error
 	for rows.Next() {
 		blob := reflect.New(schemaType)
 		components := []interface{}{}
-		for i := 0; i < schemaType.NumField(); i++ {
-			field := schemaType.Field(i)
-			if getOptions(field).Ignore {
-				continue
-			}
+		for _, field := range info.Fields {
 			log.Tracef("Field: %s, type=%s, kind=%s", field.Name, field.Type.Name(), field.Type.Kind())
 			if field.Type.Kind() == reflect.Ptr {
 				log.Tracef("Field: %s, type=%s, kind=%s", field.Name, field.Type.Elem().Name(), field.Type.Elem().Kind())
 
 			}
-			placeholder, err := getInterface(field.Name, field.Type, blob.Elem().Field(i))
+			placeholder, err := getInterface(field.Name, field.Type, blob.Elem().Field(field.Index[0]))
 			if err != nil {
 				return results, err
 			}
@@ -221,9 +595,9 @@ func (db *DB) FindAll(schema interface{}, queries Queries) ([]interface{}, error
 	return results, nil
 }
 
-// Find retrieves the first object of a schema that satisfies the queries
-func (db *DB) Find(schema interface{}, queries Queries) (interface{}, error) {
-	blobs, err := db.FindAll(schema, queries)
+// find retrieves the first object of a schema that satisfies the queries
+func (session *session) find(ctx context.Context, schema interface{}, queries Queries) (interface{}, error) {
+	blobs, err := session.findAll(ctx, schema, queries)
 	if err != nil {
 		return nil, err
 	}
@@ -233,33 +607,140 @@ func (db *DB) Find(schema interface{}, queries Queries) (interface{}, error) {
 	return blobs[0], nil
 }
 
-// UpdateAll updates all objects of a schema that satisfy the queries
-func (db *DB) UpdateAll(schema interface{}, queries Queries) error {
-	log := db.Logger.Child(nil, "update")
+// updateAll updates all objects of a schema that satisfy the queries
+func (session *session) updateAll(ctx context.Context, schema interface{}, queries Queries) (gosql.Result, error) {
+	log := session.Logger.Child(nil, "update")
 	schemaType, _ := getTypeAndValue(schema)
-	table := strings.ToLower(schemaType.Name())
+	info := getSchemaInfo(schemaType, session.Naming)
+	table := info.Table
 
 	log = log.Record("table", table)
 	log.Tracef("Schema %s => table=%s", schemaType.Name(), table)
-	statement, parms := UpdateStatement{}.With(db).Build(table, getColumns(schemaType), queries)
+	statement, parms := UpdateStatement{}.With(session.DB).Build(table, info.Columns, queries)
+	if len(statement) == 0 {
+		return nil, errors.ArgumentInvalid.With("queries", "no column to set or no condition").WithStack()
+	}
 	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
-	_, err := db.db.Exec(statement, parms...)
-	return err
+	return session.exec.ExecContext(ctx, statement, parms...)
 }
 
-// DeleteAll deletes all objects of a schema that satisfy the queries
-func (db *DB) DeleteAll(schema interface{}, queries Queries) error {
-	log := db.Logger.Child(nil, "delete_all")
+// deleteAll deletes all objects of a schema that satisfy the queries
+func (session *session) deleteAll(ctx context.Context, schema interface{}, queries Queries) (gosql.Result, error) {
+	log := session.Logger.Child(nil, "delete_all")
 	schemaType, _ := getTypeAndValue(schema)
-	table := strings.ToLower(schemaType.Name())
+	info := getSchemaInfo(schemaType, session.Naming)
+	table := info.Table
 
 	log = log.Record("table", table)
 	log.Tracef("Schema %s => table=%s", schemaType.Name(), table)
-	columns := getColumns(schemaType)
-	statement, parms := DeleteStatement{}.With(db).Build(table, columns, queries)
+	statement, parms := DeleteStatement{}.With(session.DB).Build(table, info.Columns, queries)
 	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
-	_, err := db.db.Exec(statement, parms...)
-	return err
+	return session.exec.ExecContext(ctx, statement, parms...)
+}
+
+// count counts the objects of a schema that satisfy the queries
+func (session *session) count(ctx context.Context, schema interface{}, queries Queries) (int64, error) {
+	log := session.Logger.Child(nil, "count")
+	schemaType, _ := getTypeAndValue(schema)
+	table := getSchemaInfo(schemaType, session.Naming).Table
+
+	log = log.Record("table", table)
+	log.Tracef("Schema %s => table=%s", schemaType.Name(), table)
+	statement := fmt.Sprintf("SELECT COUNT(*) FROM %s", session.Dialect.Quote(table))
+	where, parms := queries.WhereClauseWith(session.Dialect, []interface{}{})
+	if len(where) > 0 {
+		statement = statement + " WHERE " + where
+	}
+	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
+	var count int64
+	err := session.exec.QueryRowContext(ctx, statement, parms...).Scan(&count)
+	return count, err
+}
+
+// update updates the non key columns of a blob, the row is matched on the key columns
+//
+// If no row matched, an errors.NotFound is returned
+func (session *session) update(ctx context.Context, blob interface{}) error {
+	queries, err := getKeyQueries(blob, session.Naming, true)
+	if err != nil {
+		return err
+	}
+	result, err := session.updateAll(ctx, blob, queries)
+	if err != nil {
+		return err
+	}
+	return session.checkMatched(ctx, blob, queries, result, true)
+}
+
+// delete deletes the row of a blob, the row is matched on the key columns
+//
+// If no row matched, an errors.NotFound is returned
+func (session *session) delete(ctx context.Context, blob interface{}) error {
+	queries, err := getKeyQueries(blob, session.Naming, false)
+	if err != nil {
+		return err
+	}
+	result, err := session.deleteAll(ctx, blob, queries)
+	if err != nil {
+		return err
+	}
+	return session.checkMatched(ctx, blob, queries, result, false)
+}
+
+// affected returns the AffectedResult of a statement that updated or deleted the objects of a schema that satisfy the queries
+//
+// If the options say the queries must match and no row was affected, an errors.NotFound is returned.
+// If the driver cannot tell how many rows were affected, the rows are considered matched
+func (session *session) affected(ctx context.Context, schema interface{}, queries Queries, options AffectOptions, result gosql.Result, err error, update bool) (AffectedResult, error) {
+	if err != nil {
+		return AffectedResult{}, err
+	}
+	count, err := result.RowsAffected()
+	if err != nil {
+		return AffectedResult{RowsAffected: -1}, nil
+	}
+	if options.MustMatch && count == 0 {
+		if matched, err := session.matched(ctx, schema, queries, update); err != nil || matched {
+			return AffectedResult{}, err
+		}
+		schemaType, _ := getTypeAndValue(schema)
+		where, _ := queries.WhereClauseWith(session.Dialect, []interface{}{})
+		return AffectedResult{}, errors.NotFound.With(getSchemaInfo(schemaType, session.Naming).Table, where).WithStack()
+	}
+	return AffectedResult{RowsAffected: count}, nil
+}
+
+// checkMatched returns an errors.NotFound if the result of a statement on the row of a blob did not affect any row
+//
+// If the driver cannot tell how many rows were affected, the row is considered matched.
+// If the statement is an UPDATE and the Dialect does not count the matched rows, the row is looked for
+func (session *session) checkMatched(ctx context.Context, blob interface{}, queries Queries, result gosql.Result, update bool) error {
+	if count, err := result.RowsAffected(); err != nil || count > 0 {
+		return nil
+	}
+	if matched, err := session.matched(ctx, blob, queries, update); err != nil || matched {
+		return err
+	}
+	blobType, blobValue := getTypeAndValue(blob)
+	info := getSchemaInfo(blobType, session.Naming)
+	keys := []string{}
+	for _, field := range info.Fields {
+		if field.Options.PrimaryKey {
+			keys = append(keys, fmt.Sprintf("%v", blobValue.Field(field.Index[0]).Interface()))
+		}
+	}
+	return errors.NotFound.With(info.Table, strings.Join(keys, ", ")).WithStack()
+}
+
+// matched tells if an UPDATE that affected no row matched rows that it did not change
+//
+// This is checked only when the Dialect does not count the matched rows (see Dialect.CountsMatchedRows)
+func (session *session) matched(ctx context.Context, schema interface{}, queries Queries, update bool) (bool, error) {
+	if !update || session.Dialect.CountsMatchedRows() {
+		return false, nil
+	}
+	count, err := session.count(ctx, schema, queries)
+	return count > 0, err
 }
 
 // private methods
@@ -272,48 +753,95 @@ func getTypeAndValue(blob interface{}) (reflect.Type, reflect.Value) {
 	return blobType, reflect.ValueOf(blob)
 }
 
-func getColumns(schemaType reflect.Type) []string {
-	columns := []string{}
-	for i := 0; i < schemaType.NumField(); i++ {
-		field := schemaType.Field(i)
-		options := getOptions(field)
-		if options.Ignore {
+// getKeyQueries builds the Queries that match the row of a blob on its key columns
+//
+// If withValues is true, the Queries also set the other columns to the values of the blob
+func getKeyQueries(blob interface{}, naming NamingStrategy, withValues bool) (Queries, error) {
+	blobType, blobValue := getTypeAndValue(blob)
+	info := getSchemaInfo(blobType, naming)
+	queries := Queries{}
+	keys, sets := 0, 0
+	for _, field := range info.Fields {
+		if !field.Options.PrimaryKey && !withValues {
 			continue
 		}
-		column := strings.ToLower(field.Name)
-		if len(options.ColumnName) > 0 {
-			column = options.ColumnName
+		value, err := getColumnValue(field.StructField, field.Options, blobValue.Field(field.Index[0]))
+		if err != nil {
+			return queries, err
 		}
-		if len(options.ForeignKey) > 0 {
-			column = column + "_" + strings.ToLower(options.ForeignKey)
-
+		if field.Options.PrimaryKey {
+			queries.Add(field.Column, QueryEqual, value)
+			keys++
+		} else {
+			queries.Add(field.Column, QuerySet, value)
+			sets++
 		}
-		columns = append(columns, column)
 	}
-	return columns
+	if keys == 0 {
+		return queries, errors.ArgumentMissing.With("key").WithStack()
+	}
+	if withValues && sets == 0 {
+		return queries, errors.ArgumentInvalid.With("blob", "no column to update besides its key").WithStack()
+	}
+	return queries, nil
 }
 
 type fieldOptions struct {
-	PrimaryKey bool
-	Index      bool
-	Ignore     bool
-	ColumnName string
-	ColumnType string
-	ForeignKey string
+	PrimaryKey    bool
+	Index         bool
+	IndexName     string
+	IndexWhere    string
+	Unique        bool
+	Nullable      bool
+	Default       string
+	Check         string
+	AutoIncrement bool
+	Ignore        bool
+	ColumnName    string
+	ColumnType    string
+	ForeignKey    string
+	OnDelete      string
+	OnUpdate      string
 }
 
 func getOptions(field reflect.StructField) fieldOptions {
-	options := fieldOptions{Ignore: false}
-	for i, option := range strings.Split(field.Tag.Get("sql"), ",") {
+	options := fieldOptions{Ignore: false, Nullable: field.Type.Kind() == reflect.Ptr}
+	for i, option := range splitOptions(field.Tag.Get("sql")) {
 		name := strings.ToLower(strings.TrimSpace(option)) 
 		if strings.HasPrefix(name, "foreign=") {
 			options.ForeignKey = strings.TrimSpace(strings.Split(option, "=")[1])
+		} else if strings.HasPrefix(name, "index=") {
+			options.Index = true
+			options.IndexName = strings.TrimSpace(strings.SplitN(option, "=", 2)[1])
+		} else if strings.HasPrefix(name, "unique=") {
+			options.Index = true
+			options.Unique = true
+			options.IndexName = strings.TrimSpace(strings.SplitN(option, "=", 2)[1])
+		} else if strings.HasPrefix(name, "where=") {
+			options.IndexWhere = strings.TrimSpace(strings.SplitN(option, "=", 2)[1])
+		} else if strings.HasPrefix(name, "ondelete=") {
+			options.OnDelete = strings.TrimSpace(strings.SplitN(name, "=", 2)[1])
+		} else if strings.HasPrefix(name, "onupdate=") {
+			options.OnUpdate = strings.TrimSpace(strings.SplitN(name, "=", 2)[1])
+		} else if strings.HasPrefix(name, "default=") {
+			options.Default = strings.TrimSpace(strings.SplitN(option, "=", 2)[1])
+		} else if strings.HasPrefix(name, "check=") {
+			options.Check = strings.TrimSpace(strings.SplitN(option, "=", 2)[1])
 		} else {
 			switch name {
 			case "index":
 				options.Index = true
+			case "unique":
+				options.Index = true
+				options.Unique = true
 			case "key":
 				options.PrimaryKey = true
+			case "null":
+				options.Nullable = true
+			case "notnull":
+				options.Nullable = false
+			case "autoincrement", "serial":
+				options.AutoIncrement = true
 			case "-":
 				options.Ignore = true
 			default:
@@ -328,37 +856,27 @@ func getOptions(field reflect.StructField) fieldOptions {
 	return options
 }
 
-func getSQLType(name string, t reflect.Type) (string, error) {
-	switch t.Kind() {
-	case reflect.Array, reflect.Slice:
-		switch t.Name() {
-		case "UUID":
-			return "UUID", nil
-		default:
-			return "", errors.ArgumentInvalid.With("typeof", name).WithStack()
+// splitOptions splits the options of a sql tag on the commas that are not within parentheses or quotes
+//
+// e.g.: `sql:"check=priority IN (1, 2, 3),default='a, b'"` has 2 options
+func splitOptions(tag string) []string {
+	options := []string{}
+	depth, quoted, start := 0, false, 0
+	for i, char := range tag {
+		switch {
+		case char == '\'':
+			quoted = !quoted
+		case quoted:
+		case char == '(':
+			depth++
+		case char == ')' && depth > 0:
+			depth--
+		case char == ',' && depth == 0:
+			options = append(options, tag[start:i])
+			start = i + 1
 		}
-	case reflect.Struct:
-		switch t.Name() {
-		case "Time":
-			return "TIMESTAMP", nil
-		default:
-			return "", errors.ArgumentInvalid.With("typeof", name).WithStack()
-		}
-	case reflect.Bool:
-		return "BOOL", nil
-	case reflect.Float32, reflect.Float64:
-		return "FLOAT8", nil
-	case reflect.String:
-		return "VARCHAR(80)", nil
-	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return "INT", nil
-	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return "INT", nil
-	case reflect.Ptr:
-		return getSQLType(name, t.Elem())
-	default:
-		return "", errors.ArgumentInvalid.With("typeof", name).WithStack()
 	}
+	return append(options, tag[start:])
 }
 
 func getInterface(fieldName string, fieldType reflect.Type, fieldValue reflect.Value) (interface{}, error) {
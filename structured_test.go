@@ -1,6 +1,8 @@
 package sql_test
 
 import (
+	"context"
+	"database/sql/driver"
 	"fmt"
 	"reflect"
 	"strings"
@@ -72,6 +74,263 @@ func (suite *StructuredSuite) TestCanCreateAndDeleteTable() {
 	suite.Assert().Nil(err, "Failed to drop the table for Mammoth")
 }
 
+type Account struct {
+	ID     string `sql:"key"`
+	Email  string `sql:"unique"`
+	Tenant string `sql:"index=account_tenant_name,varchar(40)"`
+	Name   string `sql:"index=account_tenant_name"`
+	Active bool   `sql:"index=account_active_idx,where=active"`
+}
+
+func (suite *StructuredSuite) TestCanCreateTableWithIndexes() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.CreateTable(Account{})
+	suite.Require().Nil(err, "Failed to create table")
+	suite.Assert().Equal([]string{
+		"CREATE TABLE account (id VARCHAR(80) PRIMARY KEY, email VARCHAR(80) NOT NULL, tenant VARCHAR(40) NOT NULL, name VARCHAR(80) NOT NULL, active BOOL NOT NULL)",
+		"CREATE UNIQUE INDEX account_email_idx ON account (email)",
+		"CREATE INDEX account_tenant_name ON account (tenant, name)",
+		"CREATE INDEX account_active_idx ON account (active) WHERE active",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *StructuredSuite) TestShouldReportIndexErrors() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.MySQLDialect{}
+
+	err = db.CreateTable(Account{})
+	suite.Require().NotNil(err, "MySQL does not support partial indexes")
+	var details *errors.MultiError
+	suite.Require().True(errors.As(err, &details), "Error should be an errors.MultiError")
+	suite.Require().Len(details.Errors, 1)
+	suite.Assert().Truef(errors.Is(details.Errors[0], errors.Unsupported), "Error should be an Unsupported, was: %s", details.Errors[0])
+	suite.Assert().Equal([]string{
+		"CREATE TABLE account (id VARCHAR(80) PRIMARY KEY, email VARCHAR(80) NOT NULL, tenant VARCHAR(40) NOT NULL, name VARCHAR(80) NOT NULL, active BOOLEAN NOT NULL)",
+		"CREATE UNIQUE INDEX account_email_idx ON account (email)",
+		"CREATE INDEX account_tenant_name ON account (tenant, name)",
+	}, Recorded(suite.T().Name()), "The other indexes should still be created")
+}
+
+type Ticket struct {
+	ID       int        `sql:"key,serial"`
+	Title    string     `sql:"check=length(title) > 0"`
+	Status   string     `sql:"default='open'"`
+	Priority int        `sql:"default=3,check=priority BETWEEN 1 AND 5"`
+	Assignee *string
+	Opened   *time.Time `sql:"notnull,default=CURRENT_TIMESTAMP"`
+	Notes    string     `sql:"null"`
+}
+
+func (suite *StructuredSuite) TestCanCreateTableWithConstraints() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	suite.Require().Nil(db.CreateTable(Ticket{}), "Failed to create table")
+	db.Dialect = sql.SQLiteDialect{}
+	suite.Require().Nil(db.CreateTable(Ticket{}), "Failed to create table")
+	suite.Assert().Equal([]string{
+		"CREATE TABLE ticket (id INT PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY, title VARCHAR(80) NOT NULL CHECK (length(title) > 0), status VARCHAR(80) NOT NULL DEFAULT 'open', priority INT NOT NULL DEFAULT 3 CHECK (priority BETWEEN 1 AND 5), assignee VARCHAR(80), opened TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, notes VARCHAR(80))",
+		"CREATE TABLE ticket (id INTEGER PRIMARY KEY AUTOINCREMENT, title VARCHAR(80) NOT NULL CHECK (length(title) > 0), status VARCHAR(80) NOT NULL DEFAULT 'open', priority INTEGER NOT NULL DEFAULT 3 CHECK (priority BETWEEN 1 AND 5), assignee VARCHAR(80), opened TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, notes VARCHAR(80))",
+	}, Recorded(suite.T().Name()))
+}
+
+type Membership struct {
+	Team   string `sql:"key,varchar(40)"`
+	Member int    `sql:"key"`
+	Role   string `sql:"default='member'"`
+}
+
+func (suite *StructuredSuite) TestCanCreateTableWithCompositeKey() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	suite.Require().Nil(db.CreateTable(Membership{}), "Failed to create table")
+	suite.Assert().Equal([]string{
+		"CREATE TABLE membership (team VARCHAR(40), member INT, role VARCHAR(80) NOT NULL DEFAULT 'member', PRIMARY KEY (team, member))",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *StructuredSuite) TestShouldNotCreateTableWithUnsupportedAutoIncrement() {
+	type Counter struct {
+		ID    string `sql:"key"`
+		Count int    `sql:"autoincrement"`
+	}
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	for _, dialect := range []sql.Dialect{sql.SQLiteDialect{}, sql.MySQLDialect{}} {
+		db.Dialect = dialect
+		err = db.CreateTable(Counter{})
+		suite.Require().NotNil(err, "%s supports autoincrement only on primary keys", dialect.Name())
+		suite.Assert().Truef(errors.Is(err, errors.Unsupported), "Error should be an Unsupported, was: %s", err)
+	}
+	suite.Assert().Empty(Recorded(suite.T().Name()))
+}
+
+func (suite *StructuredSuite) TestCanCreateTableWithCommasInOptions() {
+	type Shipment struct {
+		ID       string  `sql:"key"`
+		Priority int     `sql:"check=priority IN (1, 2, 3),default=1"`
+		Carrier  string  `sql:"default='UPS, Ground'"`
+		Weight   float64 `sql:"weight,decimal(10, 2),notnull"`
+	}
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	suite.Require().Nil(db.CreateTable(Shipment{}), "Failed to create table")
+	suite.Assert().Equal([]string{
+		"CREATE TABLE shipment (id VARCHAR(80) PRIMARY KEY, priority INT NOT NULL DEFAULT 1 CHECK (priority IN (1, 2, 3)), carrier VARCHAR(80) NOT NULL DEFAULT 'UPS, Ground', weight DECIMAL(10, 2) NOT NULL)",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *StructuredSuite) TestShouldNotInsertAutoIncrementZeroValues() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	now := time.Now()
+	suite.Require().Nil(db.Insert(Ticket{Title: "Broken", Status: "open", Priority: 1, Opened: &now}))
+	suite.Require().Nil(db.Insert(Ticket{ID: 12, Title: "Broken", Status: "open", Priority: 1, Opened: &now}))
+	suite.Assert().Equal([]string{
+		"INSERT INTO ticket (title, status, priority, assignee, opened, notes) VALUES ($1, $2, $3, $4, $5, $6)",
+		"INSERT INTO ticket (id, title, status, priority, assignee, opened, notes) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *StructuredSuite) TestCanInsertAndFillGeneratedFields() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	opened := time.Date(2020, 4, 1, 10, 30, 0, 0, time.UTC)
+	Respond(suite.T().Name(), "INSERT INTO ticket (title, status, priority, assignee, notes) VALUES ($1, $2, $3, $4, $5) RETURNING id, opened", []string{"id INT4", "opened TIMESTAMP"}, []driver.Value{int64(7), opened})
+	Respond(suite.T().Name(), "INSERT INTO ticket (title, status, priority, assignee, notes) OUTPUT INSERTED.id, INSERTED.opened VALUES (@p1, @p2, @p3, @p4, @p5)", []string{"id INT", "opened DATETIME2"}, []driver.Value{int64(8), opened})
+
+	ticket := Ticket{Title: "Broken", Status: "open", Priority: 1}
+	suite.Require().Nil(db.Insert(&ticket), "Failed to insert")
+	suite.Assert().Equal(7, ticket.ID)
+	suite.Require().NotNil(ticket.Opened, "The default should be filled")
+	suite.Assert().True(opened.Equal(*ticket.Opened), "Opened should be %s, was %s", opened, ticket.Opened)
+
+	ticket = Ticket{Title: "Broken", Status: "open", Priority: 1}
+	suite.Require().Nil(db.Insert(ticket), "Failed to insert")
+	suite.Assert().Equal(0, ticket.ID, "A struct that is not given by pointer cannot be filled")
+
+	db.Dialect = sql.SQLServerDialect{}
+	suite.Require().Nil(db.Insert(&ticket), "Failed to insert")
+	suite.Assert().Equal(8, ticket.ID)
+
+	db.Dialect = sql.MySQLDialect{}
+	ticket = Ticket{Title: "Broken", Status: "open", Priority: 1}
+	suite.Require().Nil(db.Insert(&ticket), "Failed to insert")
+	suite.Assert().Equal(1, ticket.ID, "The key should be filled from LastInsertId")
+	suite.Assert().Nil(ticket.Opened, "MySQL cannot return the defaults")
+	suite.Assert().Equal([]string{
+		"INSERT INTO ticket (title, status, priority, assignee, notes) VALUES ($1, $2, $3, $4, $5) RETURNING id, opened",
+		"INSERT INTO ticket (title, status, priority, assignee, notes) VALUES ($1, $2, $3, $4, $5)",
+		"INSERT INTO ticket (title, status, priority, assignee, notes) OUTPUT INSERTED.id, INSERTED.opened VALUES (@p1, @p2, @p3, @p4, @p5)",
+		"INSERT INTO ticket (title, status, priority, assignee, notes) VALUES (?, ?, ?, ?, ?)",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *StructuredSuite) TestCanInsertMany() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	persons := []Person{}
+	for i := 1; i <= 5; i++ {
+		persons = append(persons, Person{ID: fmt.Sprintf("%04d", i), Name: fmt.Sprintf("Doe %d", i), Age: 20 + i})
+	}
+	suite.Require().Nil(db.InsertMany(persons, sql.InsertManyOptions{BatchSize: 2}), "Failed to insert")
+	found, err := db.FindAll(Person{}, sql.Queries{})
+	suite.Require().Nil(err, "Failed to find persons")
+	suite.Require().Len(found, 5)
+	person, err := db.Find(Person{}, sql.Queries{}.Add("id", "0005"))
+	suite.Require().Nil(err, "Failed to find person")
+	suite.Assert().Equal("Doe 5", person.(*Person).Name)
+	suite.Assert().Equal(25, person.(*Person).Age)
+}
+
+func (suite *StructuredSuite) TestCanInsertManyByBatches() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	persons := []*Person{{ID: "1"}, {ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "5"}}
+	suite.Require().Nil(db.InsertMany(persons, sql.InsertManyOptions{BatchSize: 2}), "Failed to insert persons")
+	tickets := []Ticket{{Title: "Broken"}, {Title: "Slow"}, {ID: 12, Title: "Down"}}
+	suite.Require().Nil(db.InsertMany(tickets, sql.InsertManyOptions{}), "Failed to insert tickets")
+	db.Dialect = sql.SQLServerDialect{}
+	suite.Require().Nil(db.InsertMany(persons[:2], sql.InsertManyOptions{}), "Failed to insert persons")
+	suite.Require().Nil(db.InsertMany([]Person{}, sql.InsertManyOptions{}), "Inserting nothing should succeed")
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3), ($4, $5, $6)",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3), ($4, $5, $6)",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3)",
+		"COMMIT",
+		"BEGIN",
+		"INSERT INTO ticket (title, status, priority, assignee, notes) VALUES ($1, $2, $3, $4, $5), ($6, $7, $8, $9, $10)",
+		"INSERT INTO ticket (id, title, status, priority, assignee, notes) VALUES ($1, $2, $3, $4, $5, $6)",
+		"COMMIT",
+		"BEGIN",
+		"INSERT INTO person (id, name, age) VALUES (@p1, @p2, @p3), (@p4, @p5, @p6)",
+		"COMMIT",
+		"BEGIN",
+		"COMMIT",
+	}, Recorded(suite.T().Name()))
+	suite.Assert().Equal(21845, sql.PostgresDialect{}.MaxInsertRows(3))
+	suite.Assert().Equal(333, sql.SQLiteDialect{}.MaxInsertRows(3))
+	suite.Assert().Equal(700, sql.SQLServerDialect{}.MaxInsertRows(3))
+	suite.Assert().Equal(1000, sql.SQLServerDialect{}.MaxInsertRows(2))
+}
+
+func (suite *StructuredSuite) TestCanInsertManyWithCopy() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	persons := []Person{{ID: "1"}, {ID: "2"}}
+	suite.Require().Nil(db.InsertMany(persons, sql.InsertManyOptions{Copy: true}), "Failed to copy persons")
+	db.Dialect = sql.MySQLDialect{}
+	suite.Require().Nil(db.InsertMany(persons, sql.InsertManyOptions{Copy: true}), "Failed to insert persons")
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		"COPY person (id, name, age) FROM STDIN",
+		"COPY person (id, name, age) FROM STDIN",
+		"COPY person (id, name, age) FROM STDIN",
+		"COMMIT",
+		"BEGIN",
+		"INSERT INTO person (id, name, age) VALUES (?, ?, ?), (?, ?, ?)",
+		"COMMIT",
+	}, Recorded(suite.T().Name()), "The rows should be copied one by one, then flushed")
+}
+
+func (suite *StructuredSuite) TestShouldNotInsertManyInvalidBlobs() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.InsertMany(Person{ID: "1"}, sql.InsertManyOptions{})
+	suite.Require().NotNil(err, "Inserting a struct that is not in a slice should fail")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+
+	err = db.InsertMany([]interface{}{Person{ID: "1"}, Ticket{Title: "Broken"}}, sql.InsertManyOptions{})
+	suite.Require().NotNil(err, "Inserting different structs should fail")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	suite.Assert().Equal([]string{"BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK"}, Recorded(suite.T().Name()))
+}
+
 func (suite *StructuredSuite) TestCanInsert() {
 	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
 	suite.Require().Nil(err, "Failed to open Database")
@@ -182,6 +441,140 @@ func (suite *StructuredSuite) TestCanFindOne() {
 	suite.Assert().NotEmpty(person.ID)
 }
 
+func (suite *StructuredSuite) TestCanSelect() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer func () {
+		err := db.Close()
+		suite.Assert().Nil(err, "Failed to close the database")
+	}()
+	err = db.CreateTable(Person{})
+	suite.Require().Nil(err, "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1234", "Doe", 18, db.Logger}))
+	suite.Require().Nil(db.Insert(Person{"5678", "Doe", 58, db.Logger}))
+
+	people := []Person{}
+	err = db.Select(&people, sql.Queries{}.Add("name", "Doe"))
+	suite.Require().Nil(err)
+	suite.Require().Len(people, 2)
+	for _, person := range people {
+		suite.Assert().Equal("Doe", person.Name)
+	}
+
+	pointers := []*Person{}
+	err = db.Select(&pointers, sql.Queries{}.Add("age", sql.QueryGreater, 50))
+	suite.Require().Nil(err)
+	suite.Require().Len(pointers, 1)
+	suite.Assert().Equal("5678", pointers[0].ID)
+	suite.Assert().Equal(58, pointers[0].Age)
+
+	err = db.Select(&pointers, sql.Queries{}.Add("age", sql.QueryGreater, 80))
+	suite.Require().Nil(err)
+	suite.Assert().Len(pointers, 0)
+}
+
+func (suite *StructuredSuite) TestCanFindAllSortedAndPaged() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer func () {
+		err := db.Close()
+		suite.Assert().Nil(err, "Failed to close the database")
+	}()
+	err = db.CreateTable(Person{})
+	suite.Require().Nil(err, "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1234", "Doe", 18, db.Logger}))
+	suite.Require().Nil(db.Insert(Person{"5678", "Doe", 58, db.Logger}))
+	suite.Require().Nil(db.Insert(Person{"9012", "Doe", 32, db.Logger}))
+
+	people := []Person{}
+	err = db.Select(&people, sql.Queries{}.Add("name", "Doe").OrderBy("-age"))
+	suite.Require().Nil(err)
+	suite.Require().Len(people, 3)
+	suite.Assert().Equal([]int{58, 32, 18}, []int{people[0].Age, people[1].Age, people[2].Age})
+
+	err = db.Select(&people, sql.Queries{}.OrderBy("age").Limit(2).Offset(1))
+	suite.Require().Nil(err)
+	suite.Require().Len(people, 2)
+	suite.Assert().Equal("9012", people[0].ID)
+	suite.Assert().Equal("5678", people[1].ID)
+
+	found, err := db.Find(Person{}, sql.Queries{}.OrderBy("-age"))
+	suite.Require().Nil(err)
+	suite.Assert().Equal("5678", found.(*Person).ID)
+}
+
+func (suite *StructuredSuite) TestCanFindAllWithConditions() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer func () {
+		err := db.Close()
+		suite.Assert().Nil(err, "Failed to close the database")
+	}()
+	err = db.CreateTable(Person{})
+	suite.Require().Nil(err, "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1234", "Doe", 18, db.Logger}))
+	suite.Require().Nil(db.Insert(Person{"5678", "Smith", 58, db.Logger}))
+	suite.Require().Nil(db.Insert(Person{"9012", "Doe", 32, db.Logger}))
+
+	people := []Person{}
+	err = db.Select(&people, sql.Queries{}.Match(sql.Or(sql.Where("name", "Smith"), sql.Where("age", sql.QueryLesser, 20))).OrderBy("id"))
+	suite.Require().Nil(err)
+	suite.Require().Len(people, 2)
+	suite.Assert().Equal("1234", people[0].ID)
+	suite.Assert().Equal("5678", people[1].ID)
+
+	err = db.Select(&people, sql.Queries{}.Add("name", "Doe").Match(sql.Not(sql.Where("age", sql.QueryLesser, 20))))
+	suite.Require().Nil(err)
+	suite.Require().Len(people, 1)
+	suite.Assert().Equal("9012", people[0].ID)
+}
+
+func (suite *StructuredSuite) TestCanGet() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer func () {
+		err := db.Close()
+		suite.Assert().Nil(err, "Failed to close the database")
+	}()
+	err = db.CreateTable(Person{})
+	suite.Require().Nil(err, "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1234", "Doe", 18, db.Logger}))
+
+	person := Person{}
+	err = db.Get(&person, sql.Queries{}.Add("id", "1234"))
+	suite.Require().Nil(err)
+	suite.Assert().Equal("1234", person.ID)
+	suite.Assert().Equal("Doe", person.Name)
+	suite.Assert().Equal(18, person.Age)
+
+	err = db.Get(&person, sql.Queries{}.Add("id", "nothere"))
+	suite.Require().NotNil(err)
+	suite.Assert().True(errors.Is(err, errors.NotFound), "The error should be NotFound")
+}
+
+func (suite *StructuredSuite) TestShouldNotSelectIntoInvalidDestination() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer func () {
+		err := db.Close()
+		suite.Assert().Nil(err, "Failed to close the database")
+	}()
+	people := []Person{}
+	err = db.Select(people, sql.Queries{})
+	suite.Require().NotNil(err, "Should not select into a slice that is not a pointer")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+
+	numbers := []int{}
+	err = db.Select(&numbers, sql.Queries{})
+	suite.Require().NotNil(err, "Should not select into a slice of int")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+
+	person := Person{}
+	err = db.Get(person, sql.Queries{})
+	suite.Require().NotNil(err, "Should not get into a struct that is not a pointer")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+}
+
 func (suite *StructuredSuite) TestCanUpdate() {
 	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
 	suite.Require().Nil(err, "Failed to open Database")
@@ -211,6 +604,201 @@ func (suite *StructuredSuite) TestCanDelete() {
 	suite.Assert().Nil(err)
 }
 
+func (suite *StructuredSuite) TestCanUpdateAndDeleteAllAffected() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1234", "Doe", 18, nil}))
+	suite.Require().Nil(db.Insert(Person{"5678", "Doe", 58, nil}))
+
+	result, err := db.UpdateAllAffected(Person{}, sql.Queries{}.Add("name", "Doe").Add("age", sql.QuerySet, 25), sql.AffectOptions{})
+	suite.Require().Nil(err, "Failed to update")
+	suite.Assert().Equal(int64(2), result.RowsAffected)
+
+	result, err = db.UpdateAllAffected(Person{}, sql.Queries{}.Add("age", 99).Add("age", sql.QuerySet, 25), sql.AffectOptions{})
+	suite.Require().Nil(err, "Matching no rows should not fail unless the queries must match")
+	suite.Assert().Equal(int64(0), result.RowsAffected)
+
+	result, err = db.DeleteAllAffected(Person{}, sql.Queries{}.Add("id", "1234"), sql.AffectOptions{MustMatch: true})
+	suite.Require().Nil(err, "Failed to delete")
+	suite.Assert().Equal(int64(1), result.RowsAffected)
+}
+
+func (suite *StructuredSuite) TestShouldNotUpdateOrDeleteAllWhenNoneMustMatch() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1234", "Doe", 18, nil}))
+	mustMatch := sql.AffectOptions{MustMatch: true}
+
+	_, err = db.UpdateAllAffected(Person{}, sql.Queries{}.Add("age", 99).Add("age", sql.QuerySet, 25), mustMatch)
+	suite.Require().NotNil(err, "Updating no rows should fail when the queries must match")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		_, err := tx.DeleteAllAffected(Person{}, sql.Queries{}.Add("age", sql.QueryGreater, 50), mustMatch)
+		return err
+	})
+	suite.Require().NotNil(err, "Deleting no rows should fail when the queries must match")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+}
+
+func (suite *StructuredSuite) TestCanUpdateAllUnchangedRowsWithMySQL() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.MySQLDialect{}
+	update := "UPDATE person SET age = ? WHERE name = ?"
+	count := "SELECT COUNT(*) FROM person WHERE name = ?"
+	Affect(suite.T().Name(), update, 0)
+	Respond(suite.T().Name(), count, []string{"count INT"}, []driver.Value{int64(2)})
+
+	result, err := db.UpdateAllAffected(Person{}, sql.Queries{}.Add("name", "Doe").Add("age", sql.QuerySet, 25), sql.AffectOptions{MustMatch: true})
+	suite.Require().Nil(err, "The rows matched even if MySQL did not change them")
+	suite.Assert().Equal(int64(0), result.RowsAffected, "MySQL counts only the changed rows")
+	suite.Assert().Equal([]string{update, count}, Recorded(suite.T().Name()))
+}
+
+func (suite *StructuredSuite) TestCanUpdateAndDeleteByKey() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+	suite.Require().Nil(db.Insert(Person{"1234", "Doe", 18, nil}))
+	suite.Require().Nil(db.Insert(Person{"5678", "Smith", 58, nil}))
+
+	found, err := db.Find(Person{}, sql.Queries{}.Add("id", "1234"))
+	suite.Require().Nil(err, "Failed to find person")
+	person := found.(*Person)
+	person.Name = "Doe-Smith"
+	person.Age = 19
+	suite.Require().Nil(db.Update(person), "Failed to update person")
+	found, err = db.Find(Person{}, sql.Queries{}.Add("id", "1234"))
+	suite.Require().Nil(err, "Failed to find person")
+	suite.Assert().Equal("Doe-Smith", found.(*Person).Name)
+	suite.Assert().Equal(19, found.(*Person).Age)
+	found, err = db.Find(Person{}, sql.Queries{}.Add("id", "5678"))
+	suite.Require().Nil(err, "Failed to find person")
+	suite.Assert().Equal("Smith", found.(*Person).Name, "Other rows should not be updated")
+
+	suite.Require().Nil(db.Delete(Person{ID: "1234"}), "Failed to delete person")
+	_, err = db.Find(Person{}, sql.Queries{}.Add("id", "1234"))
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+	_, err = db.Find(Person{}, sql.Queries{}.Add("id", "5678"))
+	suite.Assert().Nil(err, "Other rows should not be deleted")
+}
+
+func (suite *StructuredSuite) TestShouldNotUpdateOrDeleteMissingRow() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	err = db.Update(Person{"1234", "Doe", 18, nil})
+	suite.Require().NotNil(err, "Updating a missing row should fail")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+	suite.Assert().Contains(err.Error(), "1234")
+
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error { return tx.Delete(Person{ID: "1234"}) })
+	suite.Require().NotNil(err, "Deleting a missing row should fail")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+}
+
+func (suite *StructuredSuite) TestShouldNotUpdateOnlyKeys() {
+	type Tag struct {
+		Name string `sql:"key"`
+	}
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.Update(Tag{"urgent"})
+	suite.Require().NotNil(err, "A struct without columns besides its key cannot be updated")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	err = db.UpdateAll(Person{}, sql.Queries{}.Add("id", "1234"))
+	suite.Require().NotNil(err, "An update needs columns to set")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	suite.Assert().Empty(Recorded(suite.T().Name()), "No statement should be sent")
+}
+
+func (suite *StructuredSuite) TestCanUpdateUnchangedRowWithMySQL() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	db.Dialect = sql.MySQLDialect{}
+	update := "UPDATE person SET name = ?, age = ? WHERE id = ?"
+	count := "SELECT COUNT(*) FROM person WHERE id = ?"
+	Affect(suite.T().Name(), update, 0)
+	Respond(suite.T().Name(), count, []string{"count INT"}, []driver.Value{int64(1)})
+
+	suite.Require().Nil(db.Update(Person{"1234", "Doe", 18, nil}), "The row matched even if MySQL did not change it")
+	suite.Assert().Equal([]string{update, count}, Recorded(suite.T().Name()))
+
+	Respond(suite.T().Name(), count, []string{"count INT"}, []driver.Value{int64(0)})
+	err = db.Update(Person{"1234", "Doe", 18, nil})
+	suite.Require().NotNil(err, "Updating a missing row should fail")
+	suite.Assert().Truef(errors.Is(err, errors.NotFound), "Error should be a NotFound, was: %s", err)
+}
+
+func (suite *StructuredSuite) TestCanUseContext() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer func () {
+		err := db.Close()
+		suite.Assert().Nil(err, "Failed to close the database")
+	}()
+	ctx := context.Background()
+	err = db.CreateTableContext(ctx, Person{})
+	suite.Require().Nil(err, "Failed to create table")
+	suite.Require().Nil(db.InsertContext(ctx, Person{"1234", "Doe", 18, db.Logger}))
+	suite.Require().Nil(db.InsertContext(ctx, Person{"5678", "Doe", 58, db.Logger}))
+	err = db.UpdateAllContext(ctx, Person{}, sql.Queries{}.Add("id", "1234").Add("age", sql.QuerySet, 25))
+	suite.Require().Nil(err)
+	found, err := db.FindContext(ctx, Person{}, sql.Queries{}.Add("id", "1234"))
+	suite.Require().Nil(err)
+	person, ok := found.(*Person)
+	suite.Require().True(ok, "The found item should be a person")
+	suite.Assert().Equal(25, person.Age)
+	err = db.DeleteAllContext(ctx, Person{}, sql.Queries{}.Add("age", sql.QueryGreater, 50))
+	suite.Require().Nil(err)
+	results, err := db.FindAllContext(ctx, Person{}, sql.Queries{})
+	suite.Require().Nil(err)
+	suite.Assert().Len(results, 1)
+}
+
+func (suite *StructuredSuite) TestShouldNotFindWithCanceledContext() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer func () {
+		err := db.Close()
+		suite.Assert().Nil(err, "Failed to close the database")
+	}()
+	err = db.CreateTable(Person{})
+	suite.Require().Nil(err, "Failed to create table")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = db.FindAllContext(ctx, Person{}, sql.Queries{})
+	suite.Require().NotNil(err, "Should not query with a canceled context")
+	suite.Assert().True(errors.Is(err, context.Canceled), "The error should be context.Canceled")
+}
+
+func (suite *StructuredSuite) TestCanUseTransactionFromContext() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		return db.InsertContext(tx.ToContext(context.Background()), Person{"1234", "Doe", 18, nil})
+	})
+	suite.Require().Nil(err)
+	recorded := Recorded(suite.T().Name())
+	suite.Require().Len(recorded, 3)
+	suite.Assert().Equal("BEGIN", recorded[0])
+	suite.Assert().True(strings.HasPrefix(recorded[1], "INSERT INTO person "), "The Insert should run in the transaction")
+	suite.Assert().Equal("COMMIT", recorded[2])
+}
+
 func (suite *StructuredSuite) TestCanCreateTableWithForeignKey() {
 	type Stuff1 struct {
 		ID       string `json:"id" sql:"key"`
@@ -255,6 +843,74 @@ func (suite *StructuredSuite) TestCanCreateTableWithForeignKeyAndType() {
 	suite.Require().Nil(err, "Failed to create table for Employee")
 }
 
+type Department struct {
+	ID   int    `sql:"key"`
+	Name string
+}
+
+type Member struct {
+	ID         int         `sql:"key"`
+	Name       string
+	Department *Department `sql:"foreign=ID,ondelete=cascade,onupdate=restrict"`
+	Mentor     *Member     `sql:"foreign=ID,ondelete=setnull"`
+}
+
+func (suite *StructuredSuite) TestCanCreateTableWithForeignKeyActions() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.CreateTable(Member{}, Department{})
+	suite.Require().Nil(err, "Failed to create tables")
+	suite.Assert().Equal([]string{
+		"CREATE TABLE department (id INT PRIMARY KEY, name VARCHAR(80) NOT NULL)",
+		"CREATE TABLE member (id INT PRIMARY KEY, name VARCHAR(80) NOT NULL, department_id INT, mentor_id INT, FOREIGN KEY (department_id) REFERENCES department (id) ON DELETE CASCADE ON UPDATE RESTRICT, FOREIGN KEY (mentor_id) REFERENCES member (id) ON DELETE SET NULL)",
+	}, Recorded(suite.T().Name()), "The referenced table should be created first")
+
+	err = db.DeleteTable(Department{}, Member{})
+	suite.Require().Nil(err, "Failed to delete tables")
+	suite.Assert().Equal([]string{
+		"DROP TABLE member",
+		"DROP TABLE department",
+	}, Recorded(suite.T().Name())[2:], "The referencing table should be deleted first")
+}
+
+func (suite *StructuredSuite) TestShouldNotCreateTableWithInvalidForeignKeyAction() {
+	type Member struct {
+		ID         int         `sql:"key"`
+		Department *Department `sql:"foreign=ID,ondelete=explode"`
+	}
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.CreateTable(Member{})
+	suite.Require().NotNil(err, "Should not create a table with an invalid foreign key action")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	suite.Assert().Empty(Recorded(suite.T().Name()))
+}
+
+type Hen struct {
+	ID  int  `sql:"key"`
+	Egg *Egg `sql:"foreign=ID"`
+}
+
+type Egg struct {
+	ID  int  `sql:"key"`
+	Hen *Hen `sql:"foreign=ID"`
+}
+
+func (suite *StructuredSuite) TestShouldNotCreateTablesWithCyclicForeignKeys() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.CreateTable(Hen{}, Egg{})
+	suite.Require().NotNil(err, "Should not create tables with cyclic foreign keys")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	suite.Assert().Empty(Recorded(suite.T().Name()))
+}
+
 func (suite *StructuredSuite) TestCanUseForeignKeys() {
 	manager := &Manager{uuid.New(), "Joe", suite.Logger}
 	employee := &Employee{uuid.New(), "John", manager, suite.Logger}
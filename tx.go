@@ -0,0 +1,394 @@
+package sql
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+)
+
+// Tx is an in-progress database transaction
+//
+// A transaction must end with a call to Commit or Rollback.
+// After a call to Commit or Rollback, all operations on the transaction fail with ErrTxDone.
+//
+// Tx supports the same structured operations as DB (CreateTable, Insert, FindAll, etc)
+//
+// A Tx can be nested in another Tx (see Tx.Begin and Tx.InTransaction), in which case it runs within a savepoint
+// of its parent: committing releases the savepoint, rolling back undoes only what was done since the savepoint.
+type Tx struct {
+	tx         *gosql.Tx
+	ctx        context.Context
+	savepoint  string
+	savepoints *int
+	done       bool
+	DB         *DB
+	Logger     *logger.Logger
+}
+
+// Begin starts a transaction. The default isolation level is dependent on the driver
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction.
+//
+// The provided context is used until the transaction is committed or rolled back.
+// If the context is canceled, the sql package will roll back the transaction.
+// Tx.Commit will return an error if the context provided to BeginTx is canceled.
+//
+// The provided TxOptions is optional and may be nil if defaults should be used.
+// If a non-default isolation level is used that the driver doesn't support, an error will be returned
+func (db *DB) BeginTx(ctx context.Context, options *gosql.TxOptions) (*Tx, error) {
+	tx, err := db.db.BeginTx(ctx, options)
+	if err != nil {
+		return nil, errors.RuntimeError.Wrap(err)
+	}
+	db.Logger.Child("tx", "begin").Debugf("Transaction started")
+	return &Tx{
+		tx:         tx,
+		ctx:        ctx,
+		savepoints: new(int),
+		DB:         db,
+		Logger:     db.Logger.Child("tx", "tx"),
+	}, nil
+}
+
+// InTransaction runs the given func in a new transaction
+//
+// The transaction is committed if the func succeeds, it is rolled back if the func returns an error or panics.
+// If the func panics, the panic is propagated after the transaction is rolled back
+//
+// The operations of the DB that the func runs with Tx.Context join the transaction.
+//
+// If the context already contains a transaction (see Tx.ToContext), the func runs in a nested transaction of it
+func (db *DB) InTransaction(ctx context.Context, txFunc func(tx *Tx) error) error {
+	if parent, err := TxFromContext(ctx); err == nil {
+		return parent.InTransaction(ctx, txFunc)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	return tx.run(txFunc)
+}
+
+// Begin starts a transaction nested in this transaction through a savepoint
+func (tx *Tx) Begin() (*Tx, error) {
+	return tx.BeginTx(context.Background())
+}
+
+// BeginTx starts a transaction nested in this transaction through a savepoint
+func (tx *Tx) BeginTx(ctx context.Context) (*Tx, error) {
+	*tx.savepoints++
+	savepoint := fmt.Sprintf("savepoint_%d", *tx.savepoints)
+	if _, err := tx.tx.ExecContext(ctx, getDialect(tx.DB).Savepoint(savepoint)); err != nil {
+		return nil, errors.RuntimeError.Wrap(err)
+	}
+	tx.Logger.Debugf("Savepoint %s created", savepoint)
+	return &Tx{
+		tx:         tx.tx,
+		ctx:        ctx,
+		savepoint:  savepoint,
+		savepoints: tx.savepoints,
+		DB:         tx.DB,
+		Logger:     tx.Logger.Record("savepoint", savepoint),
+	}, nil
+}
+
+// InTransaction runs the given func in a transaction nested in this transaction through a savepoint
+//
+// The nested transaction is committed if the func succeeds, it is rolled back if the func returns an error or panics.
+// Rolling back the nested transaction does not roll back this transaction, which stays usable
+func (tx *Tx) InTransaction(ctx context.Context, txFunc func(tx *Tx) error) error {
+	nested, err := tx.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	return nested.run(txFunc)
+}
+
+// Context returns the context the transaction was started with, the transaction is stored in it (see ToContext)
+//
+// The operations of the DB that run with this context join the transaction
+func (tx *Tx) Context() context.Context {
+	return tx.ToContext(tx.ctx)
+}
+
+// ToContext stores the transaction to the given context
+func (tx *Tx) ToContext(parent context.Context) context.Context {
+	return context.WithValue(parent, txContextKey, tx)
+}
+
+// TxFromContext retrieves a Tx stored in the given context
+func TxFromContext(context context.Context) (*Tx, error) {
+	if tx, ok := context.Value(txContextKey).(*Tx); ok {
+		return tx, nil
+	}
+	return nil, errors.ArgumentMissing.With("Tx").WithStack()
+}
+
+// run runs the given func in the transaction, then commits or rolls back
+func (tx *Tx) run(txFunc func(tx *Tx) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Logger.Errorf("Panic in transaction, rolling back: %v", r)
+			_ = tx.Rollback()
+			panic(r)
+		}
+		if err != nil {
+			tx.Logger.Errorf("Failed to run the transaction, rolling back", err)
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				tx.Logger.Errorf("Failed to rollback the transaction", rollbackErr)
+			}
+			return
+		}
+		err = tx.Commit()
+	}()
+	return txFunc(tx)
+}
+
+// Commit commits the transaction
+//
+// If the transaction is nested, its savepoint is released
+func (tx *Tx) Commit() error {
+	if len(tx.savepoint) == 0 {
+		tx.Logger.Debugf("Committing transaction")
+		return tx.tx.Commit()
+	}
+	if tx.done {
+		return gosql.ErrTxDone
+	}
+	tx.done = true
+	tx.Logger.Debugf("Releasing savepoint")
+	return tx.releaseSavepoint()
+}
+
+// Rollback aborts the transaction
+//
+// If the transaction is nested, it is rolled back to its savepoint, which is released, and the parent transaction stays usable
+func (tx *Tx) Rollback() error {
+	if len(tx.savepoint) == 0 {
+		tx.Logger.Debugf("Rolling back transaction")
+		return tx.tx.Rollback()
+	}
+	if tx.done {
+		return gosql.ErrTxDone
+	}
+	tx.done = true
+	tx.Logger.Debugf("Rolling back to savepoint")
+	if _, err := tx.tx.ExecContext(tx.ctx, getDialect(tx.DB).RollbackToSavepoint(tx.savepoint)); err != nil {
+		return err
+	}
+	return tx.releaseSavepoint()
+}
+
+// releaseSavepoint releases the savepoint of a nested transaction, if the Dialect releases savepoints
+func (tx *Tx) releaseSavepoint() error {
+	if statement := getDialect(tx.DB).ReleaseSavepoint(tx.savepoint); len(statement) > 0 {
+		_, err := tx.tx.ExecContext(tx.ctx, statement)
+		return err
+	}
+	return nil
+}
+
+// Exec executes a query without returning any rows. The args are for any placeholder parameters in the query
+func (tx *Tx) Exec(query string, args ...interface{}) (gosql.Result, error) {
+	return tx.tx.Exec(query, args...)
+}
+
+// ExecContext executes a query without returning any rows. The args are for any placeholder parameters in the query
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (gosql.Result, error) {
+	return tx.tx.ExecContext(ctx, query, args...)
+}
+
+// Query executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query
+func (tx *Tx) Query(query string, args ...interface{}) (*gosql.Rows, error) {
+	return tx.tx.Query(query, args...)
+}
+
+// QueryContext executes a query that returns rows, typically a SELECT. The args are for any placeholder parameters in the query
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*gosql.Rows, error) {
+	return tx.tx.QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that is expected to return at most one row.
+// QueryRow always returns a non-nil value. Errors are deferred until Row's Scan method is called.
+func (tx *Tx) QueryRow(query string, args ...interface{}) *gosql.Row {
+	return tx.tx.QueryRow(query, args...)
+}
+
+// QueryRowContext executes a query that is expected to return at most one row.
+// QueryRowContext always returns a non-nil value. Errors are deferred until Row's Scan method is called.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *gosql.Row {
+	return tx.tx.QueryRowContext(ctx, query, args...)
+}
+
+// CreateTable creates the SQL Tables of the given schemas within the transaction
+func (tx *Tx) CreateTable(schemas ...interface{}) error {
+	return tx.CreateTableContext(context.Background(), schemas...)
+}
+
+// CreateTableContext creates the SQL Tables of the given schemas within the transaction
+func (tx *Tx) CreateTableContext(ctx context.Context, schemas ...interface{}) error {
+	return tx.session().createTables(ctx, schemas)
+}
+
+// DeleteTable deletes (drops) the SQL tables that represent the given schemas within the transaction
+func (tx *Tx) DeleteTable(schemas ...interface{}) error {
+	return tx.DeleteTableContext(context.Background(), schemas...)
+}
+
+// DeleteTableContext deletes (drops) the SQL tables that represent the given schemas within the transaction
+func (tx *Tx) DeleteTableContext(ctx context.Context, schemas ...interface{}) error {
+	return tx.session().deleteTables(ctx, schemas)
+}
+
+// Insert insert a blob in its SQL table within the transaction
+//
+// If the blob is a pointer, its fields generated by the database (autoincrement keys, defaults) are filled with the inserted values
+func (tx *Tx) Insert(blob interface{}) error {
+	return tx.InsertContext(context.Background(), blob)
+}
+
+// InsertContext insert a blob in its SQL table within the transaction
+func (tx *Tx) InsertContext(ctx context.Context, blob interface{}) error {
+	return tx.session().insert(ctx, blob)
+}
+
+// InsertMany inserts the blobs of a slice in their SQL table within the transaction
+func (tx *Tx) InsertMany(blobs interface{}, options InsertManyOptions) error {
+	return tx.InsertManyContext(context.Background(), blobs, options)
+}
+
+// InsertManyContext inserts the blobs of a slice in their SQL table within the transaction
+func (tx *Tx) InsertManyContext(ctx context.Context, blobs interface{}, options InsertManyOptions) error {
+	return tx.session().insertMany(ctx, blobs, options)
+}
+
+// Upsert inserts a blob in its SQL table, or updates the existing row if the blob conflicts with it, within the transaction
+func (tx *Tx) Upsert(blob interface{}, options UpsertOptions) error {
+	return tx.UpsertContext(context.Background(), blob, options)
+}
+
+// UpsertContext inserts a blob in its SQL table, or updates the existing row if the blob conflicts with it, within the transaction
+func (tx *Tx) UpsertContext(ctx context.Context, blob interface{}, options UpsertOptions) error {
+	return tx.session().upsert(ctx, blob, options)
+}
+
+// FindAll retrieves all objects of a schema that satisfy the queries within the transaction
+func (tx *Tx) FindAll(schema interface{}, queries Queries) ([]interface{}, error) {
+	return tx.FindAllContext(context.Background(), schema, queries)
+}
+
+// FindAllContext retrieves all objects of a schema that satisfy the queries within the transaction
+func (tx *Tx) FindAllContext(ctx context.Context, schema interface{}, queries Queries) ([]interface{}, error) {
+	return tx.session().findAll(ctx, schema, queries)
+}
+
+// Find retrieves the first object of a schema that satisfies the queries within the transaction
+func (tx *Tx) Find(schema interface{}, queries Queries) (interface{}, error) {
+	return tx.FindContext(context.Background(), schema, queries)
+}
+
+// FindContext retrieves the first object of a schema that satisfies the queries within the transaction
+func (tx *Tx) FindContext(ctx context.Context, schema interface{}, queries Queries) (interface{}, error) {
+	return tx.session().find(ctx, schema, queries)
+}
+
+// UpdateAll updates all objects of a schema that satisfy the queries within the transaction
+func (tx *Tx) UpdateAll(schema interface{}, queries Queries) error {
+	return tx.UpdateAllContext(context.Background(), schema, queries)
+}
+
+// UpdateAllContext updates all objects of a schema that satisfy the queries within the transaction
+func (tx *Tx) UpdateAllContext(ctx context.Context, schema interface{}, queries Queries) error {
+	_, err := tx.session().updateAll(ctx, schema, queries)
+	return err
+}
+
+// UpdateAllAffected updates all objects of a schema that satisfy the queries within the transaction and tells how many rows were updated
+func (tx *Tx) UpdateAllAffected(schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	return tx.UpdateAllAffectedContext(context.Background(), schema, queries, options)
+}
+
+// UpdateAllAffectedContext updates all objects of a schema that satisfy the queries within the transaction and tells how many rows were updated
+func (tx *Tx) UpdateAllAffectedContext(ctx context.Context, schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	session := tx.session()
+	result, err := session.updateAll(ctx, schema, queries)
+	return session.affected(ctx, schema, queries, options, result, err, true)
+}
+
+// Update updates the row of a blob in its SQL table within the transaction
+//
+// If no row matched, an errors.NotFound is returned
+func (tx *Tx) Update(blob interface{}) error {
+	return tx.UpdateContext(context.Background(), blob)
+}
+
+// UpdateContext updates the row of a blob in its SQL table within the transaction
+func (tx *Tx) UpdateContext(ctx context.Context, blob interface{}) error {
+	return tx.session().update(ctx, blob)
+}
+
+// DeleteAll deletes all objects of a schema that satisfy the queries within the transaction
+func (tx *Tx) DeleteAll(schema interface{}, queries Queries) error {
+	return tx.DeleteAllContext(context.Background(), schema, queries)
+}
+
+// DeleteAllContext deletes all objects of a schema that satisfy the queries within the transaction
+func (tx *Tx) DeleteAllContext(ctx context.Context, schema interface{}, queries Queries) error {
+	_, err := tx.session().deleteAll(ctx, schema, queries)
+	return err
+}
+
+// DeleteAllAffected deletes all objects of a schema that satisfy the queries within the transaction and tells how many rows were deleted
+func (tx *Tx) DeleteAllAffected(schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	return tx.DeleteAllAffectedContext(context.Background(), schema, queries, options)
+}
+
+// DeleteAllAffectedContext deletes all objects of a schema that satisfy the queries within the transaction and tells how many rows were deleted
+func (tx *Tx) DeleteAllAffectedContext(ctx context.Context, schema interface{}, queries Queries, options AffectOptions) (AffectedResult, error) {
+	session := tx.session()
+	result, err := session.deleteAll(ctx, schema, queries)
+	return session.affected(ctx, schema, queries, options, result, err, false)
+}
+
+// Delete deletes the row of a blob from its SQL table within the transaction
+//
+// If no row matched, an errors.NotFound is returned
+func (tx *Tx) Delete(blob interface{}) error {
+	return tx.DeleteContext(context.Background(), blob)
+}
+
+// DeleteContext deletes the row of a blob from its SQL table within the transaction
+func (tx *Tx) DeleteContext(ctx context.Context, blob interface{}) error {
+	return tx.session().delete(ctx, blob)
+}
+
+// Select retrieves all objects that satisfy the queries into destination within the transaction
+func (tx *Tx) Select(destination interface{}, queries Queries) error {
+	return tx.SelectContext(context.Background(), destination, queries)
+}
+
+// SelectContext retrieves all objects that satisfy the queries into destination within the transaction
+func (tx *Tx) SelectContext(ctx context.Context, destination interface{}, queries Queries) error {
+	return tx.session().selectAll(ctx, destination, queries)
+}
+
+// Get retrieves the first object that satisfies the queries into destination within the transaction
+func (tx *Tx) Get(destination interface{}, queries Queries) error {
+	return tx.GetContext(context.Background(), destination, queries)
+}
+
+// GetContext retrieves the first object that satisfies the queries into destination within the transaction
+func (tx *Tx) GetContext(ctx context.Context, destination interface{}, queries Queries) error {
+	return tx.session().get(ctx, destination, queries)
+}
+
+// session returns a session that runs within the transaction
+func (tx *Tx) session() *session {
+	return &session{DB: tx.DB, exec: tx.tx}
+}
@@ -0,0 +1,247 @@
+package sql_test
+
+import (
+	"context"
+	gosql "database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	_ "github.com/proullon/ramsql/driver"
+	"github.com/stretchr/testify/suite"
+)
+
+type TxSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+func TestTxSuite(t *testing.T) {
+	suite.Run(t, new(TxSuite))
+}
+
+func (suite *TxSuite) TestCanCommit() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	tx, err := db.Begin()
+	suite.Require().Nil(err, "Failed to begin a transaction")
+	suite.Require().Nil(tx.Insert(Person{"1234", "Doe", 18, nil}))
+	suite.Require().Nil(tx.Insert(Person{"5678", "Doe", 58, nil}))
+	found, err := tx.FindAll(Person{}, sql.Queries{}.Add("name", "Doe"))
+	suite.Require().Nil(err)
+	suite.Assert().Len(found, 2)
+	suite.Require().Nil(tx.Commit(), "Failed to commit the transaction")
+
+	found, err = db.FindAll(Person{}, sql.Queries{}.Add("name", "Doe"))
+	suite.Require().Nil(err)
+	suite.Assert().Len(found, 2)
+}
+
+func (suite *TxSuite) TestCanRollback() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	tx, err := db.BeginTx(context.Background(), nil)
+	suite.Require().Nil(err, "Failed to begin a transaction")
+	suite.Require().Nil(tx.Insert(Person{"1234", "Doe", 18, nil}))
+	suite.Require().Nil(tx.Rollback(), "Failed to rollback the transaction")
+	suite.Assert().Equal(gosql.ErrTxDone, tx.Commit(), "The transaction should be done after a rollback")
+}
+
+func (suite *TxSuite) TestCanRunInTransaction() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	var transaction *sql.Tx
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		transaction = tx
+		if err := tx.Insert(Person{"1234", "Doe", 18, nil}); err != nil {
+			return err
+		}
+		return tx.UpdateAll(Person{}, sql.Queries{}.Add("id", "1234").Add("age", sql.QuerySet, 25))
+	})
+	suite.Require().Nil(err, "Failed to run the transaction")
+	suite.Assert().Equal(gosql.ErrTxDone, transaction.Rollback(), "The transaction should be committed")
+
+	found, err := db.Find(Person{}, sql.Queries{}.Add("id", "1234"))
+	suite.Require().Nil(err)
+	person, ok := found.(*Person)
+	suite.Require().True(ok, "The found item should be a person")
+	suite.Assert().Equal(25, person.Age)
+}
+
+func (suite *TxSuite) TestShouldRollbackInTransactionOnError() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	var transaction *sql.Tx
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		transaction = tx
+		if err := tx.Insert(Person{"1234", "Doe", 18, nil}); err != nil {
+			return err
+		}
+		return errors.ArgumentInvalid.With("age", 18).WithStack()
+	})
+	suite.Require().NotNil(err, "The transaction should have failed")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	suite.Assert().Equal(gosql.ErrTxDone, transaction.Commit(), "The transaction should be rolled back")
+}
+
+func (suite *TxSuite) TestShouldRollbackInTransactionOnPanic() {
+	db, err := sql.Open("ramsql", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+	suite.Require().Nil(db.CreateTable(Person{}), "Failed to create table")
+
+	var transaction *sql.Tx
+	defer func() {
+		r := recover()
+		suite.Require().NotNil(r, "Should have panicked")
+		suite.Assert().Equal("Oops", r)
+		suite.Assert().Equal(gosql.ErrTxDone, transaction.Commit(), "The transaction should be rolled back")
+	}()
+	_ = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		transaction = tx
+		if err := tx.Insert(Person{"1234", "Doe", 18, nil}); err != nil {
+			return err
+		}
+		panic("Oops")
+	})
+}
+
+func (suite *TxSuite) TestCanNestTransactions() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		if _, err := tx.Exec("INSERT 1"); err != nil {
+			return err
+		}
+		err := tx.InTransaction(context.Background(), func(nested *sql.Tx) error {
+			if _, err := nested.Exec("INSERT 2"); err != nil {
+				return err
+			}
+			return errors.ArgumentInvalid.With("insert", 2).WithStack()
+		})
+		suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+		err = tx.InTransaction(context.Background(), func(nested *sql.Tx) error {
+			_, err := nested.Exec("INSERT 3")
+			return err
+		})
+		suite.Assert().Nil(err, "Failed to run the second nested transaction")
+		_, err = tx.Exec("INSERT 4")
+		return err
+	})
+	suite.Require().Nil(err, "Failed to run the transaction")
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		"INSERT 1",
+		"SAVEPOINT savepoint_1",
+		"INSERT 2",
+		"ROLLBACK TO SAVEPOINT savepoint_1",
+		"RELEASE SAVEPOINT savepoint_1",
+		"SAVEPOINT savepoint_2",
+		"INSERT 3",
+		"RELEASE SAVEPOINT savepoint_2",
+		"INSERT 4",
+		"COMMIT",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *TxSuite) TestCanNestTransactionsFromContext() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		found, err := sql.TxFromContext(tx.Context())
+		suite.Require().Nil(err, "The transaction should be in its context")
+		suite.Assert().Equal(tx, found)
+		return db.InTransaction(tx.Context(), func(nested *sql.Tx) error {
+			found, err := sql.TxFromContext(nested.Context())
+			suite.Require().Nil(err, "The nested transaction should be in its context")
+			suite.Assert().Equal(nested, found)
+			return db.DeleteContext(nested.Context(), Membership{"core", 12, "owner"})
+		})
+	})
+	suite.Require().Nil(err, "Failed to run the transaction")
+	suite.Assert().Equal([]string{
+		"BEGIN",
+		"SAVEPOINT savepoint_1",
+		"DELETE FROM membership WHERE member = $1 AND team = $2",
+		"RELEASE SAVEPOINT savepoint_1",
+		"COMMIT",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *TxSuite) TestShouldNotCommitNestedTransactionTwice() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	tx, err := db.Begin()
+	suite.Require().Nil(err, "Failed to begin a transaction")
+	nested, err := tx.Begin()
+	suite.Require().Nil(err, "Failed to begin a nested transaction")
+	suite.Require().Nil(nested.Commit())
+	suite.Assert().Equal(gosql.ErrTxDone, nested.Commit())
+	suite.Assert().Equal(gosql.ErrTxDone, nested.Rollback())
+	suite.Require().Nil(tx.Rollback())
+}
+
+func (suite *TxSuite) TestFailsWhenTxNotStoredInContext() {
+	_, err := sql.TxFromContext(context.Background())
+	suite.Require().NotNil(err)
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+}
+
+// Suite Tools
+
+func (suite *TxSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *TxSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *TxSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *TxSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}
@@ -0,0 +1,134 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gildas/go-errors"
+)
+
+// UpsertOptions tells what Upsert does when the row conflicts with an existing row
+type UpsertOptions struct {
+	// Conflict are the columns of the primary key or of the unique index that detect the conflict, they must be inserted.
+	// They default to the key columns of the schema that are inserted, the generated keys (e.g. autoincrement) cannot conflict.
+	// MySQL detects conflicts on all the unique indexes and ignores them
+	Conflict []string
+
+	// Update are the columns to update in the existing row, they default to all the inserted columns that are not in Conflict
+	Update []string
+
+	// DoNothing keeps the existing row unchanged
+	DoNothing bool
+}
+
+// Upsert inserts a blob in its SQL table, or updates the existing row if the blob conflicts with it
+//
+// The statement is atomic, it does not race with other writers like a Find followed by an Insert or an Update does
+func (db *DB) Upsert(blob interface{}, options UpsertOptions) error {
+	return db.UpsertContext(context.Background(), blob, options)
+}
+
+// UpsertContext inserts a blob in its SQL table, or updates the existing row if the blob conflicts with it
+//
+// If the context contains a transaction (see Tx.ToContext), the blob is upserted within it
+func (db *DB) UpsertContext(ctx context.Context, blob interface{}, options UpsertOptions) error {
+	return db.session(ctx).upsert(ctx, blob, options)
+}
+
+// upsert inserts a blob in its SQL table, or updates the existing row
+func (session *session) upsert(ctx context.Context, blob interface{}, options UpsertOptions) error {
+	log := session.Logger.Child(nil, "upsert")
+	blobType, blobValue := getTypeAndValue(blob)
+	info := getSchemaInfo(blobType, session.Naming)
+	log = log.Record("table", info.Table)
+
+	queries, err := insertQueries(log, info, blobValue)
+	if err != nil {
+		return err
+	}
+	columns, parms := insertColumns(info, queries)
+	inserted := map[string]bool{}
+	for _, column := range columns {
+		inserted[column] = true
+	}
+
+	conflict := options.Conflict
+	if len(conflict) == 0 {
+		for _, field := range info.Keys() {
+			if inserted[field.Column] {
+				conflict = append(conflict, field.Column)
+			}
+		}
+	}
+	inConflict := map[string]bool{}
+	for _, column := range conflict {
+		if !inserted[column] {
+			return errors.ArgumentInvalid.With("conflict", column).WithStack()
+		}
+		inConflict[column] = true
+	}
+	update := []string{}
+	switch {
+	case options.DoNothing:
+	case len(options.Update) > 0:
+		for _, column := range options.Update {
+			if !inserted[column] {
+				return errors.ArgumentInvalid.With("update", column).WithStack()
+			}
+		}
+		update = options.Update
+	default:
+		for _, column := range columns {
+			if !inConflict[column] {
+				update = append(update, column)
+			}
+		}
+	}
+
+	statement, err := session.Dialect.Upsert(info.Table, columns, conflict, update)
+	if err != nil {
+		return err
+	}
+	log.Tracef("Statement: %s with %d parameters", statement, len(parms))
+	_, err = session.exec.ExecContext(ctx, statement, parms...)
+	return err
+}
+
+// insertClause returns the INSERT INTO ... VALUES clause of a statement whose parameters are the values of the columns
+func insertClause(dialect Dialect, table string, columns []string) string {
+	return insertRows(dialect, table, columns, 1)
+}
+
+// quoteColumns returns the quoted columns, each prefixed with the given prefix, separated by commas
+func quoteColumns(dialect Dialect, columns []string, prefix string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = prefix + dialect.Quote(column)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// onConflict returns the upsert statement of the Dialects that support INSERT ... ON CONFLICT (PostgreSQL, SQLite)
+func onConflict(dialect Dialect, table string, columns, conflict, update []string) (string, error) {
+	statement := strings.Builder{}
+	statement.WriteString(insertClause(dialect, table, columns))
+	if len(conflict) == 0 {
+		if len(update) > 0 {
+			return "", errors.ArgumentMissing.With("conflict").WithStack()
+		}
+		statement.WriteString(" ON CONFLICT DO NOTHING")
+		return statement.String(), nil
+	}
+	statement.WriteString(fmt.Sprintf(" ON CONFLICT (%s)", quoteColumns(dialect, conflict, "")))
+	if len(update) == 0 {
+		statement.WriteString(" DO NOTHING")
+		return statement.String(), nil
+	}
+	sets := make([]string, len(update))
+	for i, column := range update {
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", dialect.Quote(column), dialect.Quote(column))
+	}
+	statement.WriteString(" DO UPDATE SET " + strings.Join(sets, ", "))
+	return statement.String(), nil
+}
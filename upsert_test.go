@@ -0,0 +1,146 @@
+package sql_test
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gildas/go-errors"
+	"github.com/gildas/go-logger"
+	"github.com/gildas/go-sql"
+	"github.com/stretchr/testify/suite"
+)
+
+type UpsertSuite struct {
+	suite.Suite
+	Name   string
+	Logger *logger.Logger
+	Start  time.Time
+}
+
+func TestUpsertSuite(t *testing.T) {
+	suite.Run(t, new(UpsertSuite))
+}
+
+func (suite *UpsertSuite) TestCanUpsert() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	suite.Require().Nil(db.Upsert(Person{"1234", "Doe", 18, nil}, sql.UpsertOptions{}), "Failed to upsert")
+	suite.Require().Nil(db.Upsert(Person{"1234", "Jones", 20, nil}, sql.UpsertOptions{DoNothing: true}), "Failed to upsert")
+	suite.Require().Nil(db.Upsert(Person{"1234", "Jones", 21, nil}, sql.UpsertOptions{Update: []string{"age"}}), "Failed to upsert")
+	err = db.InTransaction(context.Background(), func(tx *sql.Tx) error {
+		return tx.Upsert(Person{"5678", "Smith", 58, nil}, sql.UpsertOptions{})
+	})
+	suite.Require().Nil(err, "Failed to upsert in a transaction")
+	suite.Assert().Equal([]string{
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET age = EXCLUDED.age",
+		"BEGIN",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age",
+		"COMMIT",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *UpsertSuite) TestCanBuildGoldenUpserts() {
+	goldens := map[sql.Dialect]string{
+		sql.PostgresDialect{}:  "INSERT INTO person (id, name, age) VALUES ($1, $2, $3) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age",
+		sql.MySQLDialect{}:     "INSERT INTO person (id, name, age) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE name = VALUES(name), age = VALUES(age)",
+		sql.SQLiteDialect{}:    "INSERT INTO person (id, name, age) VALUES (?, ?, ?) ON CONFLICT (id) DO UPDATE SET name = EXCLUDED.name, age = EXCLUDED.age",
+		sql.SQLServerDialect{}: "MERGE INTO person WITH (HOLDLOCK) AS target USING (VALUES (@p1, @p2, @p3)) AS source (id, name, age) ON target.id = source.id WHEN MATCHED THEN UPDATE SET target.name = source.name, target.age = source.age WHEN NOT MATCHED THEN INSERT (id, name, age) VALUES (source.id, source.name, source.age);",
+	}
+	for dialect, golden := range goldens {
+		name := suite.T().Name() + dialect.Name()
+		db, err := sql.Open("recorder", name, suite.Logger)
+		suite.Require().Nil(err, "Failed to open Database")
+		db.Dialect = dialect
+		suite.Require().Nil(db.Upsert(Person{"1234", "Doe", 18, nil}, sql.UpsertOptions{}), "Failed to upsert with %s", dialect.Name())
+		suite.Assert().Equal([]string{golden}, Recorded(name), "Upsert for %s", dialect.Name())
+		db.Close()
+	}
+}
+
+func (suite *UpsertSuite) TestCanUpsertWithUniqueIndexAndDoNothing() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	suite.Require().Nil(db.Upsert(Person{"1234", "Doe", 18, nil}, sql.UpsertOptions{Conflict: []string{"name"}, Update: []string{"age"}}))
+	suite.Require().Nil(db.Upsert(Person{"1234", "Doe", 18, nil}, sql.UpsertOptions{DoNothing: true}))
+	db.Dialect = sql.MySQLDialect{}
+	suite.Require().Nil(db.Upsert(Person{"1234", "Doe", 18, nil}, sql.UpsertOptions{DoNothing: true}))
+	suite.Assert().Equal([]string{
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3) ON CONFLICT (name) DO UPDATE SET age = EXCLUDED.age",
+		"INSERT INTO person (id, name, age) VALUES ($1, $2, $3) ON CONFLICT (id) DO NOTHING",
+		"INSERT INTO person (id, name, age) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE id = id",
+	}, Recorded(suite.T().Name()))
+}
+
+func (suite *UpsertSuite) TestShouldNotUpsertWithInvalidOptions() {
+	db, err := sql.Open("recorder", suite.T().Name(), suite.Logger)
+	suite.Require().Nil(err, "Failed to open Database")
+	defer db.Close()
+
+	err = db.Upsert(Person{"1234", "Doe", 18, nil}, sql.UpsertOptions{Update: []string{"nickname"}})
+	suite.Require().NotNil(err, "Updating an unknown column should fail")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+
+	type Keyless struct {
+		Name string
+		Age  int
+	}
+	db.Dialect = sql.SQLServerDialect{}
+	err = db.Upsert(Keyless{"Doe", 18}, sql.UpsertOptions{})
+	suite.Require().NotNil(err, "Upserting without conflict columns should fail")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+
+	err = db.Upsert(Ticket{Title: "Broken", Status: "open", Priority: 1}, sql.UpsertOptions{})
+	suite.Require().NotNil(err, "A generated key cannot detect the conflict")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentMissing), "Error should be an ArgumentMissing, was: %s", err)
+	err = db.Upsert(Ticket{Title: "Broken", Status: "open", Priority: 1}, sql.UpsertOptions{Conflict: []string{"id"}})
+	suite.Require().NotNil(err, "The conflict columns must be inserted")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	_, err = sql.SQLServerDialect{}.Upsert("ticket", []string{"title"}, []string{"id"}, []string{"title"})
+	suite.Require().NotNil(err, "The conflict columns must be inserted")
+	suite.Assert().Truef(errors.Is(err, errors.ArgumentInvalid), "Error should be an ArgumentInvalid, was: %s", err)
+	suite.Assert().Empty(Recorded(suite.T().Name()))
+}
+
+// Suite Tools
+
+func (suite *UpsertSuite) SetupSuite() {
+	suite.Name = strings.TrimSuffix(reflect.TypeOf(*suite).Name(), "Suite")
+	suite.Logger = logger.Create("test",
+		&logger.FileStream{
+			Path:        fmt.Sprintf("./log/test-%s.log", strings.ToLower(suite.Name)),
+			Unbuffered:  true,
+			FilterLevel: logger.TRACE,
+		},
+	).Child("test", "test")
+	suite.Logger.Infof("Suite Start: %s %s", suite.Name, strings.Repeat("=", 80-14-len(suite.Name)))
+}
+
+func (suite *UpsertSuite) TearDownSuite() {
+	if suite.T().Failed() {
+		suite.Logger.Warnf("At least one test failed, we are not cleaning")
+		suite.T().Log("At least one test failed, we are not cleaning")
+	} else {
+		suite.Logger.Infof("All tests succeeded, we are cleaning")
+	}
+	suite.Logger.Infof("Suite End: %s %s", suite.Name, strings.Repeat("=", 80-12-len(suite.Name)))
+}
+
+func (suite *UpsertSuite) BeforeTest(suiteName, testName string) {
+	suite.Logger.Infof("Test Start: %s %s", testName, strings.Repeat("-", 80-13-len(testName)))
+	suite.Start = time.Now()
+}
+
+func (suite *UpsertSuite) AfterTest(suiteName, testName string) {
+	duration := time.Since(suite.Start)
+	suite.Logger.Record("duration", duration.String()).Infof("Test End: %s %s", testName, strings.Repeat("-", 80-11-len(testName)))
+}